@@ -42,6 +42,12 @@ type CallFuncResult struct {
 	Err error
 }
 
+// 启动脚本，allFrames 为 false 时仅注入主 frame
+type bootScript struct {
+	fn        func(view *View) string
+	allFrames bool
+}
+
 type Blink struct {
 	*Config
 	IPC *IPC
@@ -56,7 +62,7 @@ type Blink struct {
 	views   map[WkeHandle]*View
 	windows map[WkeHandle]*Window
 
-	bootScripts []string
+	bootScripts []bootScript
 
 	threadID uint32 // 调用 mb api 的线程 id
 
@@ -190,6 +196,9 @@ func (mb *Blink) LoopWinMessage() {
 
 func (mb *Blink) KeepRunning() {
 
+	// 由 cmd/blink-dts 启动时，生成 TypeScript 声明后退出
+	mb.IPC.exitIfGeneratingDTS()
+
 	mb.LoopWinMessage()
 
 	<-mb.quit
@@ -445,7 +454,29 @@ func (mb *Blink) GetViewByJsExecState(es JsExecState) (view *View, exist bool) {
 }
 
 func (mb *Blink) AddBootScript(script string) {
-	mb.bootScripts = append(mb.bootScripts, script)
+	mb.AddBootScriptFunc(func(*View) string {
+		return script
+	})
+}
+
+// 添加动态生成的启动脚本，每次创建主 frame 的脚本上下文时重新生成并注入
+func (mb *Blink) AddBootScriptFunc(fn func(view *View) string) {
+	mb.bootScripts = append(mb.bootScripts, bootScript{fn: fn})
+}
+
+// 同 AddBootScriptFunc，但也会注入到 iframe 中，iframe 可能加载不受信任的页面，请确认脚本可以在其中运行
+func (mb *Blink) AddFrameBootScriptFunc(fn func(view *View) string) {
+	mb.bootScripts = append(mb.bootScripts, bootScript{fn: fn, allFrames: true})
+}
+
+// 将 GO 对象绑定为 window[name]，JS 中同步读写属性、调用方法，详见 JS.BindObject
+func (mb *Blink) BindObject(name string, obj interface{}) {
+	mb.js.BindObject(name, obj)
+}
+
+// 将 GO 函数绑定为 window[name]，JS 中同步调用，GO 返回的 error 作为 JS 异常抛出，详见 JS.BindFunction
+func (mb *Blink) BindFunction(name string, fn interface{}) {
+	mb.js.BindFunction(name, fn)
 }
 
 func (mb *Blink) GetString(str WkeString) string {
@@ -0,0 +1,65 @@
+// blink-dts 为使用 blink 的程序生成 IPC 通道的 TypeScript 声明
+//
+// 用法：
+//
+//	blink-dts [-o ipc.d.ts] [-tags 'slim'] ./path/to/app
+//
+// 以 blink_dts 标签通过 go run 启动目标程序，程序在调用 KeepRunning 时，将已通过 IPC.Handle / HandleTyped 注册的通道写入声明文件后退出，
+// 因此通道须在 KeepRunning 之前注册。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/epkgs/blink/pkg/ipc"
+)
+
+func main() {
+	out := flag.String("o", "ipc.d.ts", "声明文件的输出路径")
+	tags := flag.String("tags", "", "传递给 go run 的构建标签")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: blink-dts [-o ipc.d.ts] [-tags tags] <package>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := filepath.Abs(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	_ = os.Remove(file) // 避免误用上一次生成的文件
+
+	// 仅以 blink_dts 标签构建的程序才会生成声明，标签统一以逗号分隔
+	buildTags := append(strings.Fields(strings.ReplaceAll(*tags, ",", " ")), ipc.TAG_DTS)
+
+	args := []string{"run", "-tags", strings.Join(buildTags, ","), flag.Arg(0)}
+
+	cmd := exec.Command("go", args...)
+	cmd.Env = append(os.Environ(), ipc.ENV_DTS_OUT+"="+file)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		fmt.Fprintf(os.Stderr, "未生成声明文件，请确认程序调用了 KeepRunning: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(file)
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/epkgs/blink/internal/log"
 	dl "github.com/epkgs/blink/pkg/downloader"
+	ipcCore "github.com/epkgs/blink/pkg/ipc"
 	"github.com/epkgs/blink/pkg/utils"
 )
 
@@ -19,6 +21,20 @@ type Config struct {
 	storagePath string
 	// 设置cookie文件名
 	cookieFile string
+	// IPC 调用的默认超时时间，GO 与 JS 两端一致
+	ipcTimeout time.Duration
+	// 允许使用 IPC 的页面 origin 或 URL，为空时不限制
+	ipcOrigins []string
+	// 是否校验每条 JS -> GO 消息携带的 View 专属 nonce
+	ipcNonce bool
+	// IPC 消息记录器
+	ipcRecorder *ipcCore.Recorder
+	// GO -> JS 消息的合并发送窗口，为 0 时逐条发送
+	ipcBatch time.Duration
+	// 宽松地转换 IPC handler 的参数
+	ipcLenientArgs bool
+	// 结构体参数的字段名称区分大小写
+	ipcCaseSensitiveFields bool
 	// 默认下载器
 	Downloader *dl.Downloader
 }
@@ -32,6 +48,7 @@ func NewConfig(setups ...func(*Config)) (*Config, error) {
 		dllFile:     "blink.dll",
 		storagePath: "LocalStorage",
 		cookieFile:  "cookie.dat",
+		ipcTimeout:  ipcCore.DefaultTimeout,
 	}
 
 	conf.Downloader = dl.New(func(c *dl.Config) {
@@ -78,6 +95,72 @@ func WithCookieFile(path string) func(*Config) {
 	}
 }
 
+// 设置 IPC 调用的默认超时时间，d <= 0 时不超时
+func WithIPCTimeout(d time.Duration) func(*Config) {
+	return func(conf *Config) {
+		conf.ipcTimeout = d
+	}
+}
+
+// 只允许 origin（scheme://host[:port]）匹配 patterns 之一的页面使用 IPC，其他页面发来的消息将被拒绝并记录日志
+//
+// 按发送消息的 frame（含 iframe）的 URL 校验，只匹配 origin，不匹配路径；pattern 中的 * 匹配任意字符，但不跨越 / 和 .（见 ipc.MatchOrigin），
+// 如：WithIPCOrigins("http://localhost:*", "https://*.example.com", "file://")
+func WithIPCOrigins(patterns ...string) func(*Config) {
+	return func(conf *Config) {
+		conf.ipcOrigins = append(conf.ipcOrigins, patterns...)
+	}
+}
+
+// 为每个 View 生成随机的 nonce 写入启动脚本，JS -> GO 的每条消息都须携带该 nonce，否则被拒绝并记录日志
+//
+// nonce 保存在启动脚本的闭包中，只能拦截绕过 IPC 直接调用 __js2go 等全局函数发送的消息，
+// 如无法访问主 frame 上 IPC 的跨域 iframe 中的脚本。
+// ipc.invoke 等会自动携带 nonce，同一页面中的任何脚本都可以调用，因此不能区分页面中的可信与不可信脚本，
+// 页面会加载不可信的内容时，请配合 WithIPCOrigins 限制来源，并在 handler 中校验参数
+func WithIPCNonce() func(*Config) {
+	return func(conf *Config) {
+		conf.ipcNonce = true
+	}
+}
+
+// 将 IPC 收发的所有消息记录到 JSONL，可通过 ipcCore.Replay 回放，如：
+//
+//	f, _ := os.Create("ipc.jsonl")
+//	blink.NewApp(blink.WithIPCRecorder(blink.NewIPCRecorder(f, nil)))
+func WithIPCRecorder(rec *ipcCore.Recorder) func(*Config) {
+	return func(conf *Config) {
+		conf.ipcRecorder = rec
+	}
+}
+
+// 开启 GO -> JS 消息的合并发送：同一 View 在 window 时间内的消息合并为一次 RunJS 调用，按顺序分派
+//
+// 适用于频繁推送大量小消息（进度、日志等）的场景，window 一般取一帧的时间，如 16ms
+func WithIPCBatch(window time.Duration) func(*Config) {
+	return func(conf *Config) {
+		conf.ipcBatch = window
+	}
+}
+
+// 宽松地转换 IPC handler 的参数：无法转换的参数取零值（如 "abc" -> int 为 0），而不是返回错误
+//
+// 单个通道可通过 WithChannelStrictArgs 单独设置；同样适用于 BindFunction、BindObject 绑定的函数、方法的参数
+func WithIPCLenientArgs() func(*Config) {
+	return func(conf *Config) {
+		conf.ipcLenientArgs = true
+	}
+}
+
+// 参数为结构体时，字段名称须与 json tag（或字段名）完全一致，不再忽略大小写匹配
+//
+// 全局生效（见 ipcCore.SetCaseInsensitiveFields），同样适用于 BindObject 的属性读写
+func WithIPCCaseSensitiveFields() func(*Config) {
+	return func(conf *Config) {
+		conf.ipcCaseSensitiveFields = true
+	}
+}
+
 func WithDownloader(downloader *dl.Downloader) func(*Config) {
 	return func(conf *Config) {
 		conf.Downloader = downloader
@@ -92,6 +175,34 @@ func (conf *Config) GetTempPath() string {
 	return conf.tempPath
 }
 
+func (conf *Config) GetIPCTimeout() time.Duration {
+	return conf.ipcTimeout
+}
+
+func (conf *Config) GetIPCOrigins() []string {
+	return conf.ipcOrigins
+}
+
+func (conf *Config) GetIPCNonce() bool {
+	return conf.ipcNonce
+}
+
+func (conf *Config) GetIPCRecorder() *ipcCore.Recorder {
+	return conf.ipcRecorder
+}
+
+func (conf *Config) GetIPCBatch() time.Duration {
+	return conf.ipcBatch
+}
+
+func (conf *Config) GetIPCLenientArgs() bool {
+	return conf.ipcLenientArgs
+}
+
+func (conf *Config) GetIPCCaseSensitiveFields() bool {
+	return conf.ipcCaseSensitiveFields
+}
+
 func (conf *Config) GetDllFileABS() string {
 
 	if filepath.IsAbs(conf.dllFile) {
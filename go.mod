@@ -3,7 +3,6 @@ module github.com/epkgs/blink
 go 1.20
 
 require (
-	github.com/chebyrash/promise v0.0.0-20230709133807-42ec49ba1459
 	github.com/jlaffaye/ftp v0.2.0
 	github.com/lxn/win v0.0.0-20210218163916-a377121e959e
 	golang.org/x/sys v0.21.0
@@ -12,9 +11,5 @@ require (
 require (
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
-	github.com/panjf2000/ants/v2 v2.10.0 // indirect
-	github.com/sourcegraph/conc v0.3.0 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
-	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/sync v0.8.0 // indirect
 )
@@ -2,11 +2,14 @@ package cast
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -405,6 +408,8 @@ func ByteToBool(b byte) bool {
 }
 
 // 将map[string]interface{}转换为结构体
+//
+// 字段按 JSON 名称匹配（见 JSONFields），找不到时忽略大小写再匹配一次（见 SetFoldCase）；实现了 json.Unmarshaler、encoding.TextUnmarshaler 的字段由其自行解析
 func MapToStruct(m map[string]interface{}, s interface{}) error {
 	structValue, ok := s.(reflect.Value)
 	if !ok {
@@ -415,60 +420,178 @@ func MapToStruct(m map[string]interface{}, s interface{}) error {
 		structValue = sValue.Elem()
 	}
 
-	structType := structValue.Type()
-
-	for i := 0; i < structValue.NumField(); i++ {
-		field := structValue.Field(i)
-		fieldType := structType.Field(i)
+	for _, field := range JSONFields(structValue.Type()) {
+		mapValue, ok := lookupKey(m, field.Name)
+		if !ok {
+			continue // 字段不存在于map中，跳过
+		}
 
-		// 处理嵌套结构体
-		if fieldType.Type.Kind() == reflect.Struct {
-			// 如果字段是结构体，则递归调用mapToStruct进行填充
-			nestedStructValue := reflect.New(fieldType.Type).Elem()
-			if err := MapToStruct(m, nestedStructValue); err != nil {
-				return err
-			}
-			field.Set(nestedStructValue)
-		} else if fieldType.Type.Kind() == reflect.Ptr && fieldType.Type.Elem().Kind() == reflect.Struct {
-			// 处理指针类型的嵌套结构体
-			mapValue, ok := m[fieldType.Name]
-			if !ok {
-				continue // 字段不存在于map中，跳过
+		// `json:",string"` 的字段以字符串传递，直接解码为字段类型，避免大整数经由 float64 损失精度
+		if str, isString := mapValue.(string); isString && field.Quoted {
+			ptr := reflect.New(field.Type)
+			if err := json.Unmarshal([]byte(str), ptr.Interface()); err == nil {
+				fieldByIndexAlloc(structValue, field.Index).Set(ptr.Elem())
+				continue
 			}
+		}
+
+		val, err := toValue(field.Type, mapValue)
+		if err != nil {
+			return fmt.Errorf("Type mismatch for field '%s': %s", field.Name, err.Error())
+		}
+		fieldByIndexAlloc(structValue, field.Index).Set(val)
+	}
+
+	return nil
+}
+
+// 按键取值，找不到时忽略大小写再查找一次（见 SetFoldCase）
+func lookupKey(m map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	if caseSensitive.Load() {
+		return nil, false
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// 将 map 中的值转换为字段类型，嵌套的结构体、切片、map 逐层转换
+func toValue(t reflect.Type, input interface{}) (reflect.Value, error) {
+	if input == nil {
+		return reflect.Zero(t), nil
+	}
+
+	// 如果传入的参数是指针类型的map，则解引用取得实际的map[string]interface{}值
+	if pm, ok := input.(*map[string]interface{}); ok {
+		input = *pm
+	}
+
+	if v, handled, err := unmarshalValue(t, input); handled {
+		return v, err
+	}
+
+	in := reflect.ValueOf(input)
+	if in.Type().AssignableTo(t) {
+		return in, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := toValue(t.Elem(), input)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t.Elem())
+		v.Elem().Set(elem)
+		return v, nil
+
+	case reflect.Struct:
+		m, ok := input.(map[string]interface{})
+		if !ok {
+			break
+		}
+		v := reflect.New(t).Elem()
+		if err := MapToStruct(m, v); err != nil {
+			return reflect.Value{}, err
+		}
+		return v, nil
 
-			// 如果传入的参数是指针类型的map，则解引用取得实际的map[string]interface{}值
-			if reflect.TypeOf(mapValue).Kind() == reflect.Ptr {
-				mapValue = reflect.ValueOf(mapValue).Elem().Interface()
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			data, err := ToBytes(input)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(data).Convert(t), nil
+		}
+		if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+			break
+		}
+		v := reflect.MakeSlice(t, in.Len(), in.Len())
+		for i := 0; i < in.Len(); i++ {
+			elem, err := toValue(t.Elem(), in.Index(i).Interface())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%d]: %s", i, err.Error())
 			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
 
-			// 创建新的结构体实例并填充数据
-			nestedStructValue := reflect.New(fieldType.Type.Elem()).Elem()
-			if err := MapToStruct(mapValue.(map[string]interface{}), nestedStructValue); err != nil {
-				return err
+	case reflect.Map:
+		if in.Kind() != reflect.Map || t.Key().Kind() != reflect.String || in.Type().Key().Kind() != reflect.String {
+			break
+		}
+		v := reflect.MakeMapWithSize(t, in.Len())
+		iter := in.MapRange()
+		for iter.Next() {
+			elem, err := toValue(t.Elem(), iter.Value().Interface())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("%s: %s", iter.Key().String(), err.Error())
 			}
-			// 将新创建的结构体指针赋值给字段
-			field.Set(reflect.New(fieldType.Type.Elem()))
-			field.Elem().Set(nestedStructValue)
-		} else {
-			// 处理普通字段
-			mapValue, ok := m[fieldType.Name]
-			if !ok {
-				continue // 字段不存在于map中，跳过
+			v.SetMapIndex(iter.Key().Convert(t.Key()), elem)
+		}
+		return v, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if f, ok := numberOf(in); ok {
+			if in.Kind() == reflect.String {
+				// json.Number
+				return reflect.ValueOf(f).Convert(t), nil
 			}
+			return in.Convert(t), nil
+		}
 
-			// 检查字段类型是否匹配
-			if reflect.TypeOf(mapValue).AssignableTo(fieldType.Type) {
-				field.Set(reflect.ValueOf(mapValue))
-			} else {
-				return fmt.Errorf("Type mismatch for field '%s'", fieldType.Name)
+	case reflect.String, reflect.Bool:
+		if in.Kind() == t.Kind() {
+			return in.Convert(t), nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", input, t)
+}
+
+// 转换为字节切片，支持 []byte、base64 字符串以及由数字组成的数组
+func ToBytes(input interface{}) ([]byte, error) {
+	switch v := input.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		data, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 string: %s", err.Error())
+		}
+		return data, nil
+	case []interface{}:
+		data := make([]byte, len(v))
+		for i, item := range v {
+			n, ok := ToNumber[int](item)
+			if !ok || n < 0 || n > math.MaxUint8 {
+				return nil, fmt.Errorf("invalid byte at index %d: %v", i, item)
 			}
+			data[i] = byte(n)
 		}
+		return data, nil
 	}
 
-	return nil
+	rv := reflect.ValueOf(input)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+		return rv.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("failed to convert %T to bytes", input)
 }
 
-// 将结构体转换为 map
+// 将结构体转换为 map，键为 JSON 名称（见 JSONFields）
 func StructToMap(s interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
 
@@ -477,14 +600,15 @@ func StructToMap(s interface{}) map[string]interface{} {
 	structType := structValue.Type()
 
 	// 遍历结构体的字段
-	for i := 0; i < structValue.NumField(); i++ {
-		field := structType.Field(i)
-		fieldValue := structValue.Field(i)
+	for _, field := range JSONFields(structType) {
+		fieldValue, ok := FieldByIndex(structValue, field.Index)
+		if !ok || (field.OmitEmpty && IsEmptyValue(fieldValue)) {
+			continue
+		}
 
 		// 如果字段是结构体类型，则递归地将其转换为 map
-		if fieldValue.Kind() == reflect.Struct {
-			nestedMap := StructToMap(fieldValue.Interface())
-			result[field.Name] = nestedMap
+		if fieldValue.Kind() == reflect.Struct && !IsMarshaler(fieldValue.Type()) {
+			result[field.Name] = StructToMap(fieldValue.Interface())
 		} else {
 			// 否则直接添加到 map 中
 			result[field.Name] = fieldValue.Interface()
@@ -597,6 +721,16 @@ func Param(param reflect.Type, input interface{}) (reflectVal reflect.Value, err
 			return
 		}
 	case reflect.Slice:
+		if param.Elem().Kind() == reflect.Uint8 {
+			// []byte 及以其为底层类型的参数
+			var data []byte
+			if data, err = ToBytes(input); err != nil {
+				return
+			}
+			reflectVal = reflect.ValueOf(data).Convert(param)
+			return
+		}
+
 		inputKind := reflect.TypeOf(input).Kind()
 		switch inputKind {
 		case reflect.Slice:
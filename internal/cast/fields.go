@@ -0,0 +1,270 @@
+package cast
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync/atomic"
+)
+
+// 结构体字段，按 encoding/json 的规则解析 json tag
+type Field struct {
+	Name      string       // JSON 中的名称
+	Index     []int        // 字段路径，内嵌结构体的字段会有多级
+	Type      reflect.Type // 字段类型
+	OmitEmpty bool         // `json:",omitempty"`
+	Quoted    bool         // `json:",string"`，仅对字符串、数值、布尔类型生效
+	Tagged    bool         // 是否通过 json tag 指定了名称
+}
+
+// 获取结构体的 JSON 字段
+//
+//   - `json:"-"` 的字段会被忽略
+//   - 未指定名称的内嵌结构体，其字段会提升到外层
+//   - 同名字段按 encoding/json 的规则取舍：层级浅的优先，同层级时指定了 tag 的优先，仍无法区分则全部忽略
+func JSONFields(t reflect.Type) []Field {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var all []Field
+	collectFields(t, nil, map[reflect.Type]bool{}, &all)
+
+	// 按名称分组，保留原有顺序
+	order := []string{}
+	groups := map[string][]Field{}
+	for _, f := range all {
+		if _, exist := groups[f.Name]; !exist {
+			order = append(order, f.Name)
+		}
+		groups[f.Name] = append(groups[f.Name], f)
+	}
+
+	fields := make([]Field, 0, len(order))
+	for _, name := range order {
+		if f, ok := dominantField(groups[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+var caseSensitive atomic.Bool
+
+// 设置按名称匹配字段（FieldByName、MapToStruct）时，精确匹配失败后是否忽略大小写再匹配一次，默认开启，全局生效
+func SetFoldCase(enable bool) {
+	caseSensitive.Store(!enable)
+}
+
+// 按名称查找字段，找不到时忽略大小写再查找一次（见 SetFoldCase）
+func FieldByName(fields []Field, name string) (Field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	if caseSensitive.Load() {
+		return Field{}, false
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func collectFields(t reflect.Type, index []int, visited map[reflect.Type]bool, fields *[]Field) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if !sf.IsExported() && ft.Kind() != reflect.Struct {
+				continue
+			}
+			// 未指定名称的内嵌结构体，字段提升到外层
+			if name == "" && ft.Kind() == reflect.Struct {
+				collectFields(ft, idx, visited, fields)
+				continue
+			}
+		} else if !sf.IsExported() {
+			continue
+		}
+
+		f := Field{
+			Name:      name,
+			Index:     idx,
+			Type:      sf.Type,
+			OmitEmpty: opts.contains("omitempty"),
+			Tagged:    name != "",
+		}
+		if f.Name == "" {
+			f.Name = sf.Name
+		}
+
+		if opts.contains("string") {
+			switch sf.Type.Kind() {
+			case reflect.Bool, reflect.String,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64:
+				f.Quoted = true
+			}
+		}
+
+		*fields = append(*fields, f)
+	}
+}
+
+func dominantField(fields []Field) (Field, bool) {
+	depth := len(fields[0].Index)
+	for _, f := range fields {
+		if len(f.Index) < depth {
+			depth = len(f.Index)
+		}
+	}
+
+	var candidates []Field
+	for _, f := range fields {
+		if len(f.Index) == depth {
+			candidates = append(candidates, f)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	var tagged []Field
+	for _, f := range candidates {
+		if f.Tagged {
+			tagged = append(tagged, f)
+		}
+	}
+
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+
+	return Field{}, false
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+func (o tagOptions) contains(name string) bool {
+	s := string(o)
+	for s != "" {
+		var opt string
+		opt, s, _ = strings.Cut(s, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 按字段路径取字段，途经的内嵌结构体指针为 nil 时返回 false
+func FieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// 是否为 `json:",omitempty"` 意义上的空值
+func IsEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// 自定义序列化、反序列化的接口类型
+var (
+	JSONMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	TextMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// 是否实现了 json.Marshaler 或 encoding.TextMarshaler
+func IsMarshaler(t reflect.Type) bool {
+	return t.Implements(JSONMarshalerType) || t.Implements(TextMarshalerType)
+}
+
+// 目标类型实现了 json.Unmarshaler 或 encoding.TextUnmarshaler（仅字符串输入）时，由其自行解析
+//
+// 返回的 handled 为 false 时表示未实现，需按常规方式转换
+func unmarshalValue(t reflect.Type, input interface{}) (v reflect.Value, handled bool, err error) {
+	pt := reflect.PtrTo(t)
+
+	if pt.Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return reflect.Value{}, true, err
+		}
+		ptr := reflect.New(t)
+		if err := ptr.Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+			return reflect.Value{}, true, err
+		}
+		return ptr.Elem(), true, nil
+	}
+
+	if s, ok := input.(string); ok && pt.Implements(textUnmarshalerType) {
+		ptr := reflect.New(t)
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, true, err
+		}
+		return ptr.Elem(), true, nil
+	}
+
+	return reflect.Value{}, false, nil
+}
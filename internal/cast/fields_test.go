@@ -0,0 +1,159 @@
+package cast
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID      int    `json:"id"`
+	Created string `json:"created"`
+}
+
+type Extra struct {
+	Note string
+}
+
+type level string
+
+func (l *level) UnmarshalText(text []byte) error {
+	*l = level(strings.ToUpper(string(text)))
+	return nil
+}
+
+type record struct {
+	Base
+	*Extra
+	Name    string    `json:"name"`
+	Count   int       `json:"count,omitempty"`
+	Big     int64     `json:"big,string"`
+	Skip    string    `json:"-"`
+	Created string    `json:"created"` // 浅层的字段优先于内嵌结构体的同名字段
+	Level   level     `json:"level"`
+	At      time.Time `json:"at"`
+	hidden  string
+}
+
+func TestJSONFields(t *testing.T) {
+	type want struct {
+		name      string
+		index     []int
+		omitEmpty bool
+		quoted    bool
+	}
+
+	fields := JSONFields(reflect.TypeOf(&record{}))
+	got := make([]want, len(fields))
+	for i, f := range fields {
+		got[i] = want{f.Name, f.Index, f.OmitEmpty, f.Quoted}
+	}
+
+	expected := []want{
+		{"id", []int{0, 0}, false, false},
+		{"created", []int{6}, false, false},
+		{"Note", []int{1, 0}, false, false},
+		{"name", []int{2}, false, false},
+		{"count", []int{3}, true, false},
+		{"big", []int{4}, false, true},
+		{"level", []int{7}, false, false},
+		{"at", []int{8}, false, false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %+v\nwant %+v", got, expected)
+	}
+
+	// 同层级的同名字段：指定了 tag 的优先，都未指定则全部忽略
+	type A struct{ X, Y int }
+	type B struct {
+		X int `json:"X"`
+		Y int
+	}
+	type conflict struct {
+		A
+		B
+	}
+	fields = JSONFields(reflect.TypeOf(conflict{}))
+	if len(fields) != 1 || fields[0].Name != "X" || !reflect.DeepEqual(fields[0].Index, []int{1, 0}) {
+		t.Fatalf("conflict: %+v", fields)
+	}
+}
+
+func TestMapToStruct(t *testing.T) {
+	var r record
+	err := MapToStruct(map[string]interface{}{
+		"id":      float64(7),
+		"created": "outer",
+		"Note":    "embedded pointer",
+		"name":    "a",
+		"count":   float64(3),
+		"big":     "9007199254740993",
+		"Skip":    "x",
+		"level":   "warn",
+		"at":      "2024-01-02T03:04:05Z",
+		"hidden":  "x",
+	}, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := record{
+		Base:    Base{ID: 7},
+		Extra:   &Extra{Note: "embedded pointer"},
+		Name:    "a",
+		Count:   3,
+		Big:     9007199254740993,
+		Created: "outer",
+		Level:   "WARN",
+		At:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("got %+v\nwant %+v", r, want)
+	}
+
+	// 严格转换同样按 json tag 匹配
+	v, err := ParamStrict(reflect.TypeOf(record{}), map[string]interface{}{"id": float64(7), "big": "9007199254740993", "level": "warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Interface().(record); got.ID != 7 || got.Big != 9007199254740993 || got.Level != "WARN" {
+		t.Fatalf("ParamStrict: %+v", got)
+	}
+
+	if err := MapToStruct(map[string]interface{}{"count": "x"}, &r); err == nil {
+		t.Fatal("类型不匹配时应返回错误")
+	}
+}
+
+// 字段名称精确匹配失败后，默认忽略大小写再匹配一次，可通过 SetFoldCase 关闭
+func TestFoldCase(t *testing.T) {
+	defer SetFoldCase(true)
+
+	fields := JSONFields(reflect.TypeOf(record{}))
+	input := map[string]interface{}{"Name": "a", "COUNT": float64(1)}
+
+	for _, fold := range []bool{true, false} {
+		SetFoldCase(fold)
+
+		if _, ok := FieldByName(fields, "name"); !ok {
+			t.Errorf("fold=%v: 应精确匹配 name", fold)
+		}
+		if _, ok := FieldByName(fields, "Name"); ok != fold {
+			t.Errorf("fold=%v: FieldByName(Name) = %v", fold, ok)
+		}
+
+		var r record
+		if err := MapToStruct(input, &r); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Name == "a" && r.Count == 1; got != fold {
+			t.Errorf("fold=%v: MapToStruct = %+v", fold, r)
+		}
+
+		_, err := ParamStrict(reflect.TypeOf(record{}), input)
+		if (err == nil) != fold {
+			t.Errorf("fold=%v: ParamStrict err = %v", fold, err)
+		}
+	}
+}
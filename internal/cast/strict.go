@@ -0,0 +1,396 @@
+package cast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 严格转换失败
+type ConvertError struct {
+	Path     string // 出错的位置，如 [0].name，顶层为空
+	Expected string // 期望的 GO 类型
+	Actual   string // 实际传入值的 JS 类型，见 JSTypeOf
+	Reason   string // 附加说明
+}
+
+func (e *ConvertError) Error() string {
+	var sb strings.Builder
+	if e.Path != "" {
+		fmt.Fprintf(&sb, "%s: ", e.Path)
+	}
+	fmt.Fprintf(&sb, "无法将 %s 转换为 %s", e.Actual, e.Expected)
+	if e.Reason != "" {
+		fmt.Fprintf(&sb, "（%s）", e.Reason)
+	}
+	return sb.String()
+}
+
+// 值在 JS 中的类型名称，用于错误提示
+func JSTypeOf(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		if _, ok := v.(json.Number); ok {
+			return "number"
+		}
+		return "string"
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return "Uint8Array"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "null"
+		}
+		return JSTypeOf(rv.Elem().Interface())
+	case reflect.Func:
+		return "function"
+	default:
+		return rv.Type().String()
+	}
+}
+
+// 转换一个参数，strict 为 true 时按 ParamStrict 严格转换，否则按 Param 宽松转换（无法转换的值取零值）
+//
+// 转换过程中的 panic 同样作为错误返回
+func ConvertParam(strict bool, param reflect.Type, input interface{}) (val reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	if strict {
+		return ParamStrict(param, input)
+	}
+
+	val, err = Param(param, input)
+	switch {
+	case err != nil:
+		return val, err
+	case !val.IsValid():
+		return reflect.Zero(param), nil // 传入 null
+	case !val.Type().AssignableTo(param):
+		return val, fmt.Errorf("无法将 %s 转换为 %s", val.Type(), param)
+	}
+	return val, nil
+}
+
+// 严格转换参数：类型不匹配或有损的转换（如 "abc" -> int、1.5 -> int、300 -> uint8）均返回 *ConvertError
+//
+// 结构体按 JSON 字段名匹配（见 JSONFields），存在未定义的字段时同样返回错误
+func ParamStrict(param reflect.Type, input interface{}) (reflect.Value, error) {
+	return convertStrict(param, input, "")
+}
+
+func convertStrict(t reflect.Type, input interface{}, path string) (reflect.Value, error) {
+
+	fail := func(reason string) (reflect.Value, error) {
+		return reflect.Value{}, &ConvertError{Path: path, Expected: t.String(), Actual: JSTypeOf(input), Reason: reason}
+	}
+
+	if input == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			return reflect.Zero(t), nil
+		default:
+			return fail("")
+		}
+	}
+
+	in := reflect.ValueOf(input)
+
+	// 类型一致（如 GO 直接调用）时直接使用
+	if in.Type() == t {
+		return in, nil
+	}
+
+	if v, handled, err := unmarshalValue(t, input); handled {
+		if err != nil {
+			return fail(err.Error())
+		}
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if in.Type().Implements(t) {
+			v := reflect.New(t).Elem()
+			v.Set(in)
+			return v, nil
+		}
+		return fail("")
+
+	case reflect.Bool:
+		if in.Kind() != reflect.Bool {
+			return fail("")
+		}
+		return in.Convert(t), nil
+
+	case reflect.String:
+		if in.Kind() != reflect.String {
+			return fail("")
+		}
+		return in.Convert(t), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// GO 直接调用传入整数时，避免经由 float64 损失精度
+		if isIntKind(in.Kind()) {
+			v := reflect.New(t).Elem()
+			if v.OverflowInt(in.Int()) {
+				return fail("超出范围")
+			}
+			v.SetInt(in.Int())
+			return v, nil
+		}
+		f, ok := numberOf(in)
+		if !ok {
+			return fail("")
+		}
+		if f != math.Trunc(f) {
+			return fail("不是整数")
+		}
+		v := reflect.New(t).Elem()
+		if f < -math.MaxInt64-1 || f >= math.MaxInt64 || v.OverflowInt(int64(f)) {
+			return fail("超出范围")
+		}
+		v.SetInt(int64(f))
+		return v, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isUintKind(in.Kind()) {
+			v := reflect.New(t).Elem()
+			if v.OverflowUint(in.Uint()) {
+				return fail("超出范围")
+			}
+			v.SetUint(in.Uint())
+			return v, nil
+		}
+		f, ok := numberOf(in)
+		if !ok {
+			return fail("")
+		}
+		if f != math.Trunc(f) {
+			return fail("不是整数")
+		}
+		v := reflect.New(t).Elem()
+		if f < 0 || f >= math.MaxUint64 || v.OverflowUint(uint64(f)) {
+			return fail("超出范围")
+		}
+		v.SetUint(uint64(f))
+		return v, nil
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := numberOf(in)
+		if !ok {
+			return fail("")
+		}
+		v := reflect.New(t).Elem()
+		if v.OverflowFloat(f) {
+			return fail("超出范围")
+		}
+		v.SetFloat(f)
+		return v, nil
+
+	case reflect.Ptr:
+		elem, err := convertStrict(t.Elem(), input, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t.Elem())
+		v.Elem().Set(elem)
+		return v, nil
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			data, err := ToBytes(input)
+			if err != nil {
+				return fail("")
+			}
+			return reflect.ValueOf(data).Convert(t), nil
+		}
+		if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+			return fail("")
+		}
+		v := reflect.MakeSlice(t, in.Len(), in.Len())
+		for i := 0; i < in.Len(); i++ {
+			elem, err := convertStrict(t.Elem(), in.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+
+	case reflect.Array:
+		if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+			return fail("")
+		}
+		if in.Len() != t.Len() {
+			return fail(fmt.Sprintf("长度为 %d，期望 %d", in.Len(), t.Len()))
+		}
+		v := reflect.New(t).Elem()
+		for i := 0; i < in.Len(); i++ {
+			elem, err := convertStrict(t.Elem(), in.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+
+	case reflect.Map:
+		if in.Kind() != reflect.Map || in.Type().Key().Kind() != reflect.String {
+			return fail("")
+		}
+		v := reflect.MakeMapWithSize(t, in.Len())
+		iter := in.MapRange()
+		for iter.Next() {
+			name := iter.Key().String()
+			key, err := mapKeyStrict(t.Key(), name)
+			if err != nil {
+				return reflect.Value{}, &ConvertError{Path: joinPath(path, name), Expected: t.Key().String(), Actual: "string", Reason: "无法作为键"}
+			}
+			elem, err := convertStrict(t.Elem(), iter.Value().Interface(), joinPath(path, name))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return v, nil
+
+	case reflect.Struct:
+		if in.Kind() != reflect.Map || in.Type().Key().Kind() != reflect.String {
+			return fail("")
+		}
+		fields := JSONFields(t)
+		v := reflect.New(t).Elem()
+		iter := in.MapRange()
+		for iter.Next() {
+			name := iter.Key().String()
+			field, ok := FieldByName(fields, name)
+			if !ok {
+				return reflect.Value{}, &ConvertError{Path: joinPath(path, name), Expected: t.String(), Actual: JSTypeOf(iter.Value().Interface()), Reason: "未定义的字段"}
+			}
+
+			value := iter.Value().Interface()
+			if field.Quoted {
+				// `json:",string"` 的字段以字符串传递，直接解码为字段类型，避免大整数经由 float64 损失精度
+				s, isString := value.(string)
+				if !isString {
+					return reflect.Value{}, &ConvertError{Path: joinPath(path, name), Expected: field.Type.String(), Actual: JSTypeOf(value), Reason: "需要以字符串传递"}
+				}
+				ptr := reflect.New(field.Type)
+				if err := json.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+					return reflect.Value{}, &ConvertError{Path: joinPath(path, name), Expected: field.Type.String(), Actual: "string", Reason: err.Error()}
+				}
+				fieldByIndexAlloc(v, field.Index).Set(ptr.Elem())
+				continue
+			}
+
+			elem, err := convertStrict(field.Type, value, joinPath(path, name))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			fieldByIndexAlloc(v, field.Index).Set(elem)
+		}
+		return v, nil
+	}
+
+	if in.Type().AssignableTo(t) {
+		return in, nil
+	}
+
+	return fail("")
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// JS 传来的数值：float64，或 GO 直接调用时的整数、浮点数、json.Number
+func numberOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return f, !math.IsNaN(f) && !math.IsInf(f, 0)
+	case reflect.String:
+		if n, ok := v.Interface().(json.Number); ok {
+			f, err := n.Float64()
+			return f, err == nil
+		}
+	}
+	return 0, false
+}
+
+// JSON 对象的键只能是字符串，按 encoding/json 的规则转换为整数键
+func mapKeyStrict(t reflect.Type, name string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(name)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(name, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return v, fmt.Errorf("invalid key %q", name)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(name, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return v, fmt.Errorf("invalid key %q", name)
+		}
+		v.SetUint(n)
+	default:
+		return v, fmt.Errorf("unsupported key type %s", t)
+	}
+	return v, nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// 按字段路径取字段，途经的内嵌结构体指针为 nil 时自动创建
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,160 @@
+package cast
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// 解码时 panic 的类型
+type panicky struct{}
+
+func (*panicky) UnmarshalJSON([]byte) error {
+	panic("boom")
+}
+
+type withPanicky struct {
+	P panicky `json:"p"`
+}
+
+func TestParamStrict(t *testing.T) {
+	one := 1
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		typ   interface{} // 目标类型的零值
+		input interface{}
+		want  interface{}
+	}{
+		{"整数", 0, float64(3), 3},
+		{"GO 整数", int64(0), 5, int64(5)},
+		{"uint8", uint8(0), float64(255), uint8(255)},
+		{"浮点数", float32(0), 0.5, float32(0.5)},
+		{"字符串", "", "a", "a"},
+		{"布尔", false, true, true},
+		{"null 指针", (*int)(nil), nil, (*int)(nil)},
+		{"指针", (*int)(nil), float64(1), &one},
+		{"切片", []int(nil), []interface{}{float64(1), float64(2)}, []int{1, 2}},
+		{"null 切片", []int(nil), nil, []int(nil)},
+		{"数组", [2]string{}, []interface{}{"a", "b"}, [2]string{"a", "b"}},
+		{"整数键", map[int]string(nil), map[string]interface{}{"1": "a"}, map[int]string{1: "a"}},
+		{"结构体", person{}, map[string]interface{}{"name": "a", "age": float64(3)}, person{"a", 3}},
+		{"json.Unmarshaler", time.Time{}, "2024-01-02T03:04:05Z", tm},
+		{"接口", (*error)(nil), errors.New("e"), nil},
+	}
+
+	for _, tt := range tests {
+		typ := reflect.TypeOf(tt.typ)
+		if tt.name == "接口" {
+			typ = typ.Elem()
+		}
+		v, err := ParamStrict(typ, tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		if got := v.Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 拒绝时的错误包含出错的位置、期望的类型及实际的 JS 类型
+func TestParamStrictErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      interface{}
+		input    interface{}
+		path     string
+		expected string
+		actual   string
+		reason   string
+	}{
+		{"小数", 0, 1.5, "", "int", "number", "不是整数"},
+		{"溢出", uint8(0), float64(300), "", "uint8", "number", "超出范围"},
+		{"负数", uint(0), float64(-1), "", "uint", "number", "超出范围"},
+		{"GO 整数溢出", int8(0), 1000, "", "int8", "number", "超出范围"},
+		{"字符串数字", 0, "4", "", "int", "string", ""},
+		{"数字转字符串", "", float64(1), "", "string", "number", ""},
+		{"null", "", nil, "", "string", "null", ""},
+		{"对象转数组", []int{}, map[string]interface{}{}, "", "[]int", "object", ""},
+		{"数组元素", []int{}, []interface{}{float64(1), "x"}, "[1]", "int", "string", ""},
+		{"数组长度", [2]int{}, []interface{}{float64(1)}, "", "[2]int", "array", "长度为 1，期望 2"},
+		{"数组转结构体", person{}, []interface{}{"a"}, "", "cast.person", "array", ""},
+		{"结构体字段", person{}, map[string]interface{}{"age": "x"}, "age", "int", "string", ""},
+		{"未定义的字段", person{}, map[string]interface{}{"extra": true}, "extra", "cast.person", "boolean", "未定义的字段"},
+		{"嵌套", []person{}, []interface{}{map[string]interface{}{"name": float64(1)}}, "[0].name", "string", "number", ""},
+		{"map 键", map[int]bool{}, map[string]interface{}{"a": true}, "a", "int", "string", "无法作为键"},
+		{"解码失败", time.Time{}, "yesterday", "", "time.Time", "string", "cannot parse"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParamStrict(reflect.TypeOf(tt.typ), tt.input)
+		var ce *ConvertError
+		if !errors.As(err, &ce) {
+			t.Errorf("%s: err = %v, want *ConvertError", tt.name, err)
+			continue
+		}
+		if ce.Path != tt.path || ce.Expected != tt.expected || ce.Actual != tt.actual || !strings.Contains(ce.Reason, tt.reason) {
+			t.Errorf("%s: got %+v, want path=%q expected=%q actual=%q reason=%q", tt.name, *ce, tt.path, tt.expected, tt.actual, tt.reason)
+		}
+		if !strings.Contains(err.Error(), tt.expected) || !strings.Contains(err.Error(), tt.actual) {
+			t.Errorf("%s: 错误信息 %q 应包含期望及实际的类型", tt.name, err.Error())
+		}
+	}
+}
+
+func TestConvertParam(t *testing.T) {
+	intType := reflect.TypeOf(0)
+
+	tests := []struct {
+		name   string
+		typ    reflect.Type
+		input  interface{}
+		strict interface{} // 严格转换的结果，error 表示应返回错误
+		loose  interface{} // 宽松转换的结果，规则同上
+	}{
+		{"整数", intType, float64(3), 3, 3},
+		{"小数", intType, 1.5, errors.New(""), 1},
+		{"字符串数字", intType, "4", errors.New(""), 4},
+		{"非数字", intType, "abc", errors.New(""), 0},
+		{"null", intType, nil, errors.New(""), 0},
+		{"数字转字符串", reflect.TypeOf(""), float64(12), errors.New(""), "12"},
+		{"panic", reflect.TypeOf(withPanicky{}), map[string]interface{}{"p": "x"}, errors.New("boom"), errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		for _, strict := range []bool{true, false} {
+			want := tt.loose
+			if strict {
+				want = tt.strict
+			}
+
+			v, err := ConvertParam(strict, tt.typ, tt.input)
+			if e, ok := want.(error); ok {
+				if err == nil || !strings.Contains(err.Error(), e.Error()) {
+					t.Errorf("%s strict=%v: err = %v, want %q", tt.name, strict, err, e.Error())
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s strict=%v: %v", tt.name, strict, err)
+				continue
+			}
+			if got := v.Interface(); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s strict=%v: got %#v, want %#v", tt.name, strict, got, want)
+			}
+		}
+	}
+}
@@ -1,17 +1,17 @@
 package blink
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"reflect"
+	"io"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/chebyrash/promise"
-	"github.com/epkgs/blink/internal/cast"
 	"github.com/epkgs/blink/internal/log"
+	ipcCore "github.com/epkgs/blink/pkg/ipc"
 	"github.com/epkgs/blink/pkg/utils"
 )
 
@@ -23,91 +23,104 @@ const (
 	JS_REGISTER_HANDLER = "__register_handler"
 )
 
-type Callback interface{}
+// IPC 消息类型，为空时为普通的 invoke/sent/reply 消息
+const (
+	IPC_TYPE_STREAM = ipcCore.TYPE_STREAM // JS 发起流式调用
+	IPC_TYPE_CHUNK  = ipcCore.TYPE_CHUNK  // GO -> JS，流数据
+	IPC_TYPE_END    = ipcCore.TYPE_END    // GO -> JS，流结束（可能带有错误）
+	IPC_TYPE_CANCEL = ipcCore.TYPE_CANCEL // JS -> GO，取消流
+	IPC_TYPE_ACK    = ipcCore.TYPE_ACK    // JS -> GO，已消费一条流数据
+)
 
-type resultCallback func(result interface{}, err error)
+// 内置的错误码
+const (
+	IPC_ERR_NOT_FOUND = ipcCore.ERR_NOT_FOUND // 通道不存在
+	IPC_ERR_ARGUMENT  = ipcCore.ERR_ARGUMENT  // 参数错误
+	IPC_ERR_TIMEOUT   = ipcCore.ERR_TIMEOUT   // 调用超时
+	IPC_ERR_CANCELED  = ipcCore.ERR_CANCELED  // 调用被取消
+	IPC_ERR_FORBIDDEN = ipcCore.ERR_FORBIDDEN // 调用来源不被允许
+)
 
-// resultCallback 用于区分无须返回值的情况
-type ipcHandler func(cb resultCallback, args ...interface{})
+const (
+	CHANNEL_SOURCE_GO = ipcCore.SOURCE_GO // GO 注册的 handler
+	CHANNEL_SOURCE_JS = ipcCore.SOURCE_JS // JS 注册的 handler
+)
 
-// 封装 ipcPedding, 为 Get/Add/Del 提供锁保护
-type ipcPendding struct {
-	mu        *sync.Mutex
-	callbacks map[string]resultCallback
-}
+// 内置通道：JS 获取已注册的通道列表
+const IPC_CHANNELS = ipcCore.CHANNEL_LIST
+
+type (
+	IPCMessage       = ipcCore.Message
+	IPCError         = ipcCore.Error
+	IPCCodedError    = ipcCore.CodedError
+	IPCStream        = ipcCore.Stream
+	IPCArgumentError = ipcCore.ArgumentError
+	IPCBinary        = ipcCore.Binary
+	IPCRecorder      = ipcCore.Recorder
+	IPCRecord        = ipcCore.Record
+	IPCRecordFilter  = ipcCore.RecordFilter
+	ChannelInfo      = ipcCore.ChannelInfo
+)
 
-func newIPCPendding() *ipcPendding {
-	return &ipcPendding{
-		mu:        &sync.Mutex{},
-		callbacks: make(map[string]resultCallback),
-	}
+// 创建 IPC 消息记录器，用于 WithIPCRecorder，filter 为 nil 时记录所有消息
+func NewIPCRecorder(w io.Writer, filter IPCRecordFilter) *IPCRecorder {
+	return ipcCore.NewRecorder(w, filter)
 }
 
-func (p *ipcPendding) Add(id string, cb resultCallback) {
-	// 异步，避免阻塞
-	go func() {
-		p.mu.Lock()
-		defer p.mu.Unlock()
-		p.callbacks[id] = cb
-	}()
-
-	// 超时处理
-	go func() {
-
-		time.Sleep(10 * time.Second)
-
-		if p.mu.TryLock() {
-			defer p.mu.Unlock()
-		}
+type Callback interface{}
 
-		cb, exist := p.callbacks[id]
-		if !exist {
-			return
-		}
+// 注册 handler 时的选项
+type IPCHandleOption = ipcCore.HandleOption
 
-		delete(p.callbacks, id) // 删除等待结果的callback
+// 单独设置通道的超时时间，d <= 0 时不超时。JS 调用该通道时使用相同的超时时间
+func WithChannelTimeout(d time.Duration) IPCHandleOption {
+	return ipcCore.WithTimeout(d)
+}
 
-		cb(nil, errors.New("等待 JS Handler 处理结果超时"))
-	}()
+// 单独设置通道是否严格转换参数，见 WithIPCLenientArgs
+func WithChannelStrictArgs(strict bool) IPCHandleOption {
+	return ipcCore.WithStrictArgs(strict)
 }
 
-func (p *ipcPendding) Del(id string) {
-	// 异步，避免阻塞
-	go func() {
-		p.mu.Lock()
-		defer p.mu.Unlock()
-		delete(p.callbacks, id)
-	}()
+// 限制只有 URL 的 origin 匹配 patterns 之一的 View 才能调用该通道，GO 直接调用不受限制
+//
+// pattern 中的 * 匹配任意字符，如：WithChannelOrigins("https://*.example.com", "http://localhost:*")
+func WithChannelOrigins(patterns ...string) IPCHandleOption {
+	return ipcCore.WithOrigins(patterns...)
 }
 
-func (p *ipcPendding) Get(id string) (resultCallback, bool) {
-	cb, exist := p.callbacks[id]
-	return cb, exist
+// 对某个 View 的调用结果
+type IPCViewResult struct {
+	View   *View
+	Result interface{}
+	Err    error
 }
 
+// GO 与页面 JS 之间的 IPC
+//
+// 消息的路由、等待回复、handler 调用由 ipcCore.Router 完成，每个 View 对应一个 Transport
 type IPC struct {
-	mb *Blink
-
-	handlers map[string]ipcHandler
+	*ipcCore.Router
 
-	pendding *ipcPendding
-}
+	mb *Blink
 
-type IPCMessage struct {
-	ID      string        `json:"id"`               // 消息 ID
-	ReplyId string        `json:"replyId"`          // 回复ID
-	Channel string        `json:"channel"`          // 通道
-	Args    []interface{} `json:"args"`             // 参数
-	Result  interface{}   `json:"result,omitempty"` // 返回值，当有回复ID时，此字段有效
-	Error   string        `json:"error,omitempty"`  // 是否错误，当有回复ID时，此字段有效
+	mu         sync.Mutex
+	transports map[*View]*viewTransport
 }
 
 func newIPC(mb *Blink) *IPC {
 	ipc := &IPC{
-		mb: mb,
+		Router: ipcCore.NewRouter(),
+
+		mb:         mb,
+		transports: make(map[*View]*viewTransport),
+	}
 
-		handlers: make(map[string]ipcHandler),
-		pendding: newIPCPendding(),
+	ipc.SetTimeout(mb.Config.GetIPCTimeout())
+	ipc.SetRecorder(mb.Config.GetIPCRecorder())
+	ipc.SetStrictArgs(!mb.Config.GetIPCLenientArgs())
+	if mb.Config.GetIPCCaseSensitiveFields() {
+		ipcCore.SetCaseInsensitiveFields(false)
 	}
 
 	ipc.registerBootScript()
@@ -117,188 +130,164 @@ func newIPC(mb *Blink) *IPC {
 	return ipc
 }
 
-// GO 调用handler
-//
-//	一、GO 调用 GO handler，直接调用并返回
-//
-//	二、GO 调用 JS handler, 和 GO 调用 GO 流程一样，唯一区别是在 `invokeJS` 里调用 `ipc.Invoke` 执行的 `handler` 是转化后的 `JS handler`
-func (ipc *IPC) Invoke(channel string, args ...interface{}) (interface{}, error) {
-	handler, exist := ipc.handlers[channel]
-	if !exist {
-		msg := fmt.Sprintf("ipc channel %s not exist", channel)
-		log.Error(msg)
-		return nil, errors.New(msg)
-	}
-
-	result := make(chan interface{}, 1)
-	err := make(chan error, 1)
-
-	// 将 callback 转 chan
-	handler(func(res interface{}, e error) {
-		result <- res
-		err <- e
-	}, args...)
+// GO 注册 Handler，见 ipcCore.Router.Handle
+func (ipc *IPC) Handle(channel string, handler Callback, opts ...IPCHandleOption) {
+	ipc.Router.Handle(channel, handler, opts...)
+}
 
-	return <-result, <-err
+// 注册强类型的 GO handler，见 ipcCore.HandleTyped
+func HandleTyped[Req, Resp any](ipc *IPC, channel string, handler func(ctx context.Context, req Req) (Resp, error), opts ...IPCHandleOption) {
+	ipcCore.HandleTyped(ipc.Router, channel, handler, opts...)
 }
 
-func (ipc *IPC) Sent(channel string, args ...interface{}) error {
-	handler, exist := ipc.handlers[channel]
-	if !exist {
-		msg := fmt.Sprintf("ipc channel %s not exist", channel)
-		log.Error(msg)
-		return errors.New(msg)
-	}
+// 将 GO 对象的导出方法暴露给 JS，见 ipcCore.Router.Expose
+//
+// JS 中通过 window[name] 上的同名代理对象调用，方法名首字母小写，返回 Promise（流式方法返回 IPCStream）：
+//
+//	mb.IPC.Expose("files", &FileService{})
+//	// JS: const list = await files.list(dir)
+//
+// 代理对象在每次创建脚本上下文时安装，已打开的页面也会立即安装
+func (ipc *IPC) Expose(name string, obj interface{}, opts ...IPCHandleOption) {
+	ipc.Router.Expose(name, obj, opts...)
 
-	handler(nil, args...)
+	for _, service := range ipc.Services() {
+		if service.Name != name {
+			continue
+		}
 
-	return nil
+		data, _ := json.Marshal(service)
+		script := fmt.Sprintf(`window.top['%s'] && window.top['%s'].expose && window.top['%s'].expose(%s, window)`, JS_MB, JS_MB, JS_MB, data)
+		for _, view := range ipc.mb.GetViews() {
+			view.RunJS(script)
+		}
+	}
 }
 
-// GO 注册 Handler
+// 调用指定 View 里由 JS 注册的通道
 //
-// handler 必须为函数，参数任意，返回值最多为2个
-//   - 1个返回值：会自动判断返回值是否为 error
-//   - 2个返回值：第一个为 结果，第二个为 error
-func (ipc *IPC) Handle(channel string, handler Callback) {
-
-	// 使用反射获取处理函数的类型
-	handlerVal := reflect.ValueOf(handler)
-	if handlerVal.Kind() != reflect.Func {
-		panic(fmt.Sprintf("channel %s, handler must be a function", channel))
-	}
-
-	handlerType := handlerVal.Type()
+// 同一通道由多个 View 注册时，Invoke 只发送到最近注册的 View，Sent 则广播到所有 View
+func (ipc *IPC) InvokeView(ctx context.Context, view *View, channel string, args ...interface{}) (interface{}, error) {
+	return ipc.InvokeOn(ctx, ipc.transportOf(view), channel, args...)
+}
 
-	ipc.handlers[channel] = func(cb resultCallback, inputs ...interface{}) {
+// 调用指定 View 里由 JS 注册的通道，不等待返回值
+func (ipc *IPC) SentView(view *View, channel string, args ...interface{}) error {
+	return ipc.SentOn(ipc.transportOf(view), channel, args...)
+}
 
-		inputSize := len(inputs)
+// 并发调用所有注册了该通道的 View，按注册顺序返回结果
+func (ipc *IPC) InvokeAll(ctx context.Context, channel string, args ...interface{}) ([]IPCViewResult, error) {
+	results, err := ipc.Router.InvokeAll(ctx, channel, args...)
+	if err != nil {
+		return nil, err
+	}
 
-		// 构造参数列表
-		pCount := handlerType.NumIn()
-		isVariadic := handlerType.IsVariadic()
-		if isVariadic {
-			pCount = pCount - 1
+	viewResults := make([]IPCViewResult, 0, len(results))
+	for _, r := range results {
+		vr := IPCViewResult{Result: r.Result, Err: r.Err}
+		if t, ok := r.Transport.(*viewTransport); ok {
+			vr.View = t.view
 		}
-		inVals := make([]reflect.Value, pCount)
-		for i := 0; i < pCount; i++ {
-
-			param := handlerType.In(i)
-
-			var inputVal reflect.Value
-			var err error
-
-			if i < inputSize {
-				inputVal, err = cast.Param(param, inputs[i])
-				if err != nil {
-					cb(nil, err)
-					return
-				}
-			} else {
-				inputVal = reflect.Zero(param)
-			}
+		viewResults = append(viewResults, vr)
+	}
 
-			inVals[i] = inputVal
-		}
+	return viewResults, nil
+}
 
-		if isVariadic {
-			// 处理可变参数
-			inputs = inputs[pCount:]
-			inputSize := len(inputs)
-			elem := handlerType.In(handlerType.NumIn() - 1).Elem()
-			for i := 0; i < inputSize; i++ {
-				inputVal, err := cast.Param(elem, inputs[i])
-				if err != nil {
-					cb(nil, err)
-					log.Error(err.Error())
-					return
-				}
-				inVals = append(inVals, inputVal)
-			}
-		}
+// 一次通道调用，View 为发起调用的 View，GO 直接调用时为 nil
+type IPCCall struct {
+	*ipcCore.Call
+	View *View
+}
 
-		// 异步处理 handler
-		go func() {
-
-			defer func() {
-				if r := recover(); r != nil {
-					if err, ok := r.(error); ok {
-						log.Error("panic by ipc handler[ %v ]: %v", channel, err)
-						cb(nil, err)
-					}
-				}
-			}()
-
-			select {
-			case <-time.After(10 * time.Second):
-				log.Debug("IPC 调用超时")
-				cb(nil, errors.New("IPC 调用超时"))
-				return
-			default:
-				// 调用处理函数
-				out := handlerVal.Call(inVals)
-
-				if cb == nil {
-					return
-				}
-
-				// 处理返回值
-				if len(out) == 0 {
-					// 没有返回值
-					cb(nil, nil)
-				} else if len(out) == 1 {
-					// 只有一个返回值
-					result := out[0].Interface()
-
-					switch res := result.(type) {
-					case error:
-						cb(nil, res)
-					default:
-						cb(res, nil)
-					}
-				} else if len(out) == 2 {
-					// 有2个返回值
-					res := out[0].Interface()
-					var err error
-					switch e := out[1].Interface().(type) {
-					case error:
-						err = e
-					default:
-						err = nil
-					}
-					cb(res, err)
-				} else {
-					// 多个返回值
-					cb(nil, fmt.Errorf("more than 2 return values are not supported"))
-				}
-			}
+// 执行后续的中间件以及 handler
+type IPCNext func(call *IPCCall) (interface{}, error)
+
+// IPC 中间件，可用于鉴权、审计日志、耗时统计等
+//
+// 不调用 next 直接返回，即可拦截本次调用，返回的 error 将作为调用结果传给调用方
+type IPCMiddleware func(call *IPCCall, next IPCNext) (interface{}, error)
 
-		}()
+// 添加中间件，按添加顺序执行，对 GO 与 JS 发起的所有通道调用生效
+//
+//	mb.IPC.Use(func(call *blink.IPCCall, next blink.IPCNext) (interface{}, error) {
+//		start := time.Now()
+//		result, err := next(call)
+//		log.Printf("%s %v", call.Channel, time.Since(start))
+//		return result, err
+//	})
+func (ipc *IPC) Use(middlewares ...IPCMiddleware) {
+	for _, middleware := range middlewares {
+		middleware := middleware
+		ipc.Router.Use(func(call *ipcCore.Call, next ipcCore.Next) (interface{}, error) {
+			view, _ := ViewFromContext(call.Context)
+			return middleware(&IPCCall{Call: call, View: view}, func(c *IPCCall) (interface{}, error) {
+				return next(c.Call)
+			})
+		})
 	}
 }
 
-func (ipc *IPC) HasChannel(channel string) (exist bool) {
-	_, exist = ipc.handlers[channel]
-	return
+// 获取发起调用的 View，仅 JS 发起的调用，handler 收到的 ctx 里才有
+func ViewFromContext(ctx context.Context) (*View, bool) {
+	t, ok := ipcCore.TransportFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	vt, ok := t.(*viewTransport)
+	if !ok {
+		return nil, false
+	}
+	return vt.view, true
 }
 
 //go:embed ipc.js
 var ipcjs []byte
 
+// 超时时间在每次创建脚本上下文时写入，与 GO 端保持一致
+//
+// iframe 中也会注入，以便 CallJsFuncInFrame 及 iframe 中的 JS 调用 GO（须通过 origin 校验）
 func (ipc *IPC) registerBootScript() {
-	script := fmt.Sprintf(
-		string(ipcjs),
-		JS_MB,
-		JS_IPC,
-		JS_JS2GO,
-		JS_GO2JS,
-		JS_REGISTER_HANDLER,
-	)
+	ipc.mb.AddFrameBootScriptFunc(func(view *View) string {
+		return fmt.Sprintf(
+			string(ipcjs),
+			JS_MB,
+			JS_IPC,
+			JS_JS2GO,
+			JS_GO2JS,
+			JS_REGISTER_HANDLER,
+			IPC_CHANNELS,
+			ipc.Timeout().Milliseconds(),
+			ipc.channelTimeouts(),
+			ipc.MaxMessageSize(),
+			ipcCore.BINARY_KEY,
+			ipc.services(),
+			ipc.transportOf(view).nonce,
+		)
+	})
+}
+
+// 已暴露的 GO 对象，JSON 格式
+func (ipc *IPC) services() string {
+	data, _ := json.Marshal(ipc.Services())
+	return string(data)
+}
 
-	ipc.mb.AddBootScript(script)
+// 单独设置了超时时间的 GO 通道，JSON 格式，单位毫秒
+func (ipc *IPC) channelTimeouts() string {
+	timeouts := map[string]int64{}
+	for _, info := range ipc.Channels() {
+		if info.Source == CHANNEL_SOURCE_GO && info.Timeout != 0 {
+			timeouts[info.Channel] = info.Timeout.Milliseconds()
+		}
+	}
+
+	data, _ := json.Marshal(timeouts)
+	return string(data)
 }
 
-// JS -> GO 的消息分派、处理
+// JS -> GO 的消息，交给 View 对应的 Transport 分派
 func (ipc *IPC) registerJS2GO() {
 	ipc.mb.js.bindFunction(JS_JS2GO, 1, func(es JsExecState) {
 		arg := ipc.mb.js.Arg(es, 0)
@@ -306,84 +295,63 @@ func (ipc *IPC) registerJS2GO() {
 
 		log.Debug("JS -> GO: %s", txt)
 
-		var msg IPCMessage
-		if err := json.Unmarshal(([]byte)(txt), &msg); err != nil {
-			log.Error("JS -> GO, JSON 解析出错(%s): %s", err.Error(), txt)
-			return
-		}
-
-		if msg.ReplyId != "" {
-			ipc.mb.AddJob(func() {
-				ipc.handleJSReply(&msg)
-			})
+		view, exist := ipc.mb.GetViewByJsExecState(es)
+		if !exist {
+			log.Error("JS -> GO, 没有找到 view: %s", txt)
 			return
 		}
 
-		if msg.Channel != "" {
-			if view, exist := ipc.mb.GetViewByJsExecState(es); exist {
+		t := ipc.transportOf(view)
 
-				ipc.mb.AddJob(func() {
-					ipc.invokeByJS(view, &msg)
-				})
-			}
+		if ipc.secured(t) && !ipc.accept(t, ipc.frameURL(es), txt) {
 			return
 		}
+
+		t.receive(txt)
 	})
 }
 
-// JS 调用 handler
-func (ipc *IPC) invokeByJS(view *View, msg *IPCMessage) {
-
-	// 如果 ID 为空，则无须回复返回值
-	if msg.ID == "" {
-		_ = ipc.Sent(msg.Channel, msg.Args...)
-		return
-	}
-
-	// 调用 invoke 获取到结果
-	result, err := ipc.Invoke(msg.Channel, msg.Args...)
-
-	e := ""
-	if err != nil {
-		e = err.Error()
-		result = nil
-	}
-
-	replyMsg := IPCMessage{
-		ID:      "",
-		ReplyId: msg.ID,
-		Error:   e,
-		Result:  result,
-	}
-
-	sentMsgToView(view, replyMsg)
+// 是否需要校验 JS -> GO 的消息
+func (ipc *IPC) secured(t *viewTransport) bool {
+	return len(ipc.mb.Config.GetIPCOrigins()) > 0 || t.nonce != ""
 }
 
-func (ipc *IPC) handleJSReply(msg *IPCMessage) {
-	if msg.ReplyId == "" {
-		return
+// 校验 JS -> GO 的消息，拒绝时记录日志
+func (ipc *IPC) accept(t *viewTransport, url string, txt string) bool {
+	var msg IPCMessage
+	if err := json.Unmarshal([]byte(txt), &msg); err != nil {
+		log.Error("IPC 消息 JSON 解析出错(%s): %s", err.Error(), txt)
+		return false
 	}
 
-	cb, exist := ipc.pendding.Get(msg.ReplyId)
-	if !exist {
-		return
+	err := ipc.verify(t, url, msg.Nonce)
+	if err == nil {
+		return true
 	}
 
-	ipc.pendding.Del(msg.ReplyId) // 接收到消息就从 map 中删除
-
-	if msg.Error != "" {
-		cb(nil, errors.New(msg.Error))
-	} else {
-		cb(msg.Result, nil)
+	log.Warning("IPC 拒绝了 %s 发来的消息(%s): channel=%s type=%s", url, err.Error(), msg.Channel, msg.Type)
+
+	// 需要回复的调用直接返回错误，避免 JS 一直等待
+	// 当前处于 __js2go 回调中，回复须放到任务队列，避免重入 RunJS
+	if msg.ID != "" && msg.ReplyId == "" && msg.Type == "" {
+		data, _ := json.Marshal(IPCMessage{
+			ReplyId: msg.ID,
+			Error:   &IPCError{Code: IPC_ERR_FORBIDDEN, Message: err.Error()},
+		})
+		ipc.mb.AddJob(func() {
+			_ = t.Send(string(data))
+		})
 	}
+
+	return false
 }
 
 // JS 注册 handler 埋点
 func (ipc *IPC) registerJSHandler() {
 	// 注册 JS handler
-	ipc.mb.js.bindFunction(JS_REGISTER_HANDLER, 1, func(es JsExecState) {
-		arg := ipc.mb.js.Arg(es, 0)
-		channel := ipc.mb.js.ToString(es, arg)
+	ipc.mb.js.bindFunction(JS_REGISTER_HANDLER, 2, func(es JsExecState) {
+		channel := ipc.mb.js.ToString(es, ipc.mb.js.Arg(es, 0))
+		nonce := ipc.mb.js.ToString(es, ipc.mb.js.Arg(es, 1))
 
 		view, exist := ipc.mb.GetViewByJsExecState(es)
 		if !exist {
@@ -391,77 +359,238 @@ func (ipc *IPC) registerJSHandler() {
 			return
 		}
 
-		// 将 JS handler 转为 GO handler
-		ipc.handlers[channel] = func(cb resultCallback, args ...interface{}) {
+		t := ipc.transportOf(view)
 
-			if cb == nil {
-				msg := IPCMessage{
-					ID:      "", // ID 为空则不需要回复
-					Channel: channel,
-					Args:    args,
-				}
-				sentMsgToView(view, msg)
+		if ipc.secured(t) {
+			url := ipc.frameURL(es)
+			if err := ipc.verify(t, url, nonce); err != nil {
+				log.Warning("IPC 拒绝了 %s 注册的 JS handler(%s): channel=%s", url, err.Error(), channel)
 				return
 			}
+		}
 
-			id := utils.RandString(8) // 生成key
+		// 将 JS handler 转为 GO handler，不能覆盖 GO 注册的通道
+		if err := ipc.RegisterRemote(t, channel); err != nil {
+			log.Warning("IPC 拒绝了 %s 注册的 JS handler(%s)", view.GetURL(), err.Error())
+		}
+	})
+}
 
-			msg := IPCMessage{
-				ID:      id,
-				Channel: channel,
-				Args:    args,
-			}
+// 发起调用的 frame 的 URL
+//
+// es 为调用方 frame 的脚本上下文，子 frame（如跨域的 iframe）可直接调用 __js2go 等全局函数，不能以主 frame 的 URL 校验
+func (ipc *IPC) frameURL(es JsExecState) string {
+	return ipc.mb.js.ToString(es, ipc.mb.js.Eval(es, "return location.href;"))
+}
+
+// 校验 JS -> GO 的消息：发送消息的 frame 的 origin 须在允许的范围内（见 WithIPCOrigins），开启 nonce 校验时须携带 View 的 nonce（见 WithIPCNonce）
+func (ipc *IPC) verify(t *viewTransport, url string, nonce string) error {
+	if patterns := ipc.mb.Config.GetIPCOrigins(); len(patterns) > 0 {
+		origin := ipcCore.ParseOrigin(url)
 
-			ipc.pendding.Add(id, cb) // 添加到等待结果的 map
+		allowed := false
+		for _, pattern := range patterns {
+			if origin != "" && ipcCore.MatchOrigin(pattern, origin) {
+				allowed = true
+				break
+			}
+		}
 
-			sentMsgToView(view, msg)
+		if !allowed {
+			return fmt.Errorf("页面 %s 不允许使用 IPC", url)
 		}
-	})
+	}
+
+	if t.nonce != "" && nonce != t.nonce {
+		return fmt.Errorf("nonce 校验失败")
+	}
+
+	return nil
+}
+
+// 调用页面主 frame 的 JS 函数，等待其返回值，返回 Promise 时等待其完成
+//
+// funcName 可为以 . 分隔的路径，如 app.store.dispatch，调用时 this 为 app.store。
+// 函数不存在时返回 ERR_NOT_FOUND 错误（错误信息中包含缺失的路径），JS 抛出的异常同样以 error 返回。
+// ctx 取消后不再等待；ctx 未设置超时时，使用默认的超时时间
+func (ipc *IPC) CallJsFunc(ctx context.Context, view *View, funcName string, args ...interface{}) (interface{}, error) {
+	return ipc.callJsFunc(ctx, ipc.transportOf(view), funcName, args)
+}
+
+// 调用指定 frame（如 iframe）里的 JS 函数，函数从该 frame 的 window 开始查找，见 IPC.CallJsFunc
+//
+// 仅支持与主 frame 同源的 frame，跨域的 frame 无法访问主 frame 的 IPC，调用将超时
+func (ipc *IPC) CallJsFuncInFrame(ctx context.Context, view *View, frame WkeWebFrameHandle, funcName string, args ...interface{}) (interface{}, error) {
+	if view.IsMainFrame(frame) {
+		return ipc.CallJsFunc(ctx, view, funcName, args...)
+	}
+	return ipc.callJsFunc(ctx, &frameTransport{view: view, frame: frame}, funcName, args)
 }
 
-func (ipc *IPC) CallJsFunc(view *View, funcName string, args ...interface{}) *promise.Promise[any] {
+func (ipc *IPC) callJsFunc(ctx context.Context, t ipcCore.Transport, funcName string, args []interface{}) (interface{}, error) {
 
 	newArgs := make([]interface{}, 0, len(args)+1)
 	newArgs = append(newArgs, funcName)
 	newArgs = append(newArgs, args...)
 
-	id := utils.RandString(8) // 生成key
+	return ipc.Call(ctx, t, "callJsFunc", newArgs...)
+}
+
+// 调用页面 JS 的全局函数，并将返回值转换为 T，见 IPC.CallJsFunc
+//
+//	name, err := blink.CallJsFunc[string](ctx, view, "getName")
+func CallJsFunc[T any](ctx context.Context, view *View, funcName string, args ...interface{}) (T, error) {
+	result, err := view.mb.IPC.CallJsFunc(ctx, view, funcName, args...)
+	return decodeJsResult[T](funcName, result, err)
+}
+
+// 调用指定 frame 里的 JS 函数，并将返回值转换为 T，见 IPC.CallJsFuncInFrame
+func CallJsFuncInFrame[T any](ctx context.Context, view *View, frame WkeWebFrameHandle, funcName string, args ...interface{}) (T, error) {
+	result, err := view.mb.IPC.CallJsFuncInFrame(ctx, view, frame, funcName, args...)
+	return decodeJsResult[T](funcName, result, err)
+}
+
+func decodeJsResult[T any](funcName string, result interface{}, err error) (T, error) {
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	out, err := ipcCore.Decode[T](result)
+	if err != nil {
+		return out, fmt.Errorf("JS 函数 %s 的返回值无法转换为 %T: %w", funcName, out, err)
+	}
+
+	return out, nil
+}
+
+// 获取 View 对应的 Transport，首次获取时接入 Router，View 销毁后断开
+//
+// 主 frame 的脚本上下文释放（刷新、跳转）时，该 View 注册的通道随之失效
+func (ipc *IPC) transportOf(view *View) *viewTransport {
+	ipc.mu.Lock()
+	defer ipc.mu.Unlock()
 
-	msg := IPCMessage{
-		ID:      id,
-		Channel: "callJsFunc",
-		Args:    newArgs,
+	if t, exist := ipc.transports[view]; exist {
+		return t
 	}
 
-	resolve := func(any) {}
-	reject := func(error) {}
-	p := promise.New(func(resolv func(any), rej func(error)) {
-		resolve = resolv
-		reject = rej
+	t := &viewTransport{view: view}
+	if batch := ipc.mb.Config.GetIPCBatch(); batch > 0 {
+		t.batcher = ipcCore.NewBatcher(batch, ipcCore.DefaultMaxMessageSize, t.sendBatch)
+	}
+	if ipc.mb.Config.GetIPCNonce() {
+		t.nonce = utils.RandString(32)
+	}
+	ipc.transports[view] = t
+	ipc.Attach(t)
+
+	view.OnWillReleaseScriptContext(func(frameId WkeWebFrameHandle, context uintptr, worldId int) {
+		if view.IsMainFrame(frameId) {
+			if t.batcher != nil {
+				t.batcher.Discard()
+			}
+			ipc.Reset(t)
+		}
 	})
 
-	cb := func(result any, err error) {
-		if err != nil {
-			reject(err)
-		} else {
-			resolve(result)
+	view.OnDestroy(func() {
+		ipc.mu.Lock()
+		delete(ipc.transports, view)
+		ipc.mu.Unlock()
+
+		if t.batcher != nil {
+			t.batcher.Close()
 		}
+		ipc.Detach(t)
+	})
+
+	return t
+}
+
+// 基于 View 的 Transport：通过 RunJS 调用 window.top.__go2js 发送，由 __js2go 接收
+type viewTransport struct {
+	view    *View
+	nonce   string           // View 专属的 nonce，未开启校验时为空
+	batcher *ipcCore.Batcher // 合并发送，未开启（WithIPCBatch）时为 nil，逐条发送
+
+	mu        sync.Mutex
+	onReceive func(data string)
+}
+
+func (t *viewTransport) Send(data string) error {
+
+	log.Debug("GO -> JS: %s", data)
+
+	if t.batcher != nil {
+		return t.batcher.Add(data)
 	}
 
-	ipc.pendding.Add(id, cb)
+	t.view.RunJS(fmt.Sprintf(`window.top['%s'](%q)`, JS_GO2JS, data))
+	return nil
+}
+
+// 将合并的消息通过一次 __go2js 调用发送，参数为消息数组
+func (t *viewTransport) sendBatch(batch []string) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `window.top['%s']([`, JS_GO2JS)
+	for i, data := range batch {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%q", data)
+	}
+	sb.WriteString("])")
+
+	t.view.RunJS(sb.String())
+}
+
+// 记录中的对端标识：View 的句柄
+func (t *viewTransport) Name() string {
+	return fmt.Sprintf("view:%d", t.view.Hwnd)
+}
+
+// 页面 URL 的 origin，用于 WithChannelOrigins
+func (t *viewTransport) Origin() string {
+	return ipcCore.ParseOrigin(t.view.GetURL())
+}
+
+func (t *viewTransport) OnReceive(fn func(data string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onReceive = fn
+}
 
-	sentMsgToView(view, msg)
+func (t *viewTransport) receive(data string) {
+	t.mu.Lock()
+	fn := t.onReceive
+	t.mu.Unlock()
+
+	if fn != nil {
+		fn(data)
+	}
+}
 
-	return p
+// 发送到指定 frame 的 Transport，仅用于 GO 调用该 frame 里的 JS，回复仍经由 View 的 Transport 接收
+type frameTransport struct {
+	view  *View
+	frame WkeWebFrameHandle
 }
 
-func sentMsgToView(view *View, msg IPCMessage) {
+func (t *frameTransport) Send(data string) error {
+
+	script := fmt.Sprintf(`window['%s'](%q)`, JS_GO2JS, data)
+
+	log.Debug("GO -> JS(frame %d): %s", t.frame, data)
 
-	msgTxt, _ := json.Marshal(msg)
+	t.view.RunJsByFrame(t.frame, script)
 
-	script := fmt.Sprintf(`window.top['%s'](%q)`, JS_GO2JS, msgTxt)
+	return nil
+}
 
-	log.Debug("GO -> JS: %s", msgTxt)
+func (t *frameTransport) OnReceive(fn func(data string)) {}
 
-	view.RunJS(script)
+// 记录中的对端标识：View 的句柄与 frame
+func (t *frameTransport) Name() string {
+	return fmt.Sprintf("view:%d/frame:%d", t.view.Hwnd, t.frame)
 }
@@ -5,59 +5,286 @@
     const JS_JS2GO = '%s';
     const JS_GO2JS = '%s';
     const JS_REGISTER_HANDLER = '%s';
+    const IPC_CHANNELS = '%s';
+    const IPC_TIMEOUT = %d; // 默认超时时间（毫秒），小于等于 0 时不超时
+    const CHANNEL_TIMEOUTS = %s; // 单独设置了超时时间的 GO 通道（毫秒），小于 0 时不超时
+    const IPC_MAX_MESSAGE_SIZE = %d; // 单条消息的最大长度，超过后分片发送，小于等于 0 时不分片
+    const BINARY_KEY = '%s'; // 二进制数据的封装：{ [BINARY_KEY]: '<base64>' }
+    const IPC_SERVICES = %s; // 通过 Expose 暴露的 GO 对象
+    const IPC_NONCE = '%s'; // View 专属的 nonce，每条发往 GO 的消息都须携带，为空时不校验
+
+    // 启动脚本注入每个 frame，IPC 只在主 frame 中创建，子 frame 共用 window.top 上的 IPC
+    // 子 frame 仅预留自己的 __go2js，供指定 frame 调用（如 CallJsFuncInFrame）时使用，收到的消息交给主 frame 分派
+    // ! 跨域的子 frame 无法访问 window.top，不支持指定 frame 调用
+    if (window !== window.top) {
+        const go2top = window.top[JS_GO2JS];
+        define(window, JS_GO2JS, (msgTxt) => go2top(msgTxt, window));
+        return;
+    }
+
+    // 已注入过（同一脚本上下文重复执行）时保留原有的 IPC
+    if (Object.prototype.hasOwnProperty.call(window, JS_GO2JS)) return;
 
     // MB
 
-    window.top[JS_MB] = window.top[JS_MB] || {}
-    const mb = window.top[JS_MB];
-    mb.newMsg = newMsg;
-    mb.replyWaiting = mb.replyWaiting || {};
-    mb.handlers = mb.handlers || {};
+    // GO 预埋的函数在启动时取得，之后页面脚本替换 window 上的同名函数也无法截获 nonce
+    const js2go = window[JS_JS2GO];
+    const registerHandlerToGo = window[JS_REGISTER_HANDLER];
+
+    // 内部状态只保存在闭包中，不挂到 window.top 上，避免页面脚本伪造回复、替换 handler 或篡改订阅
+    const replyWaiting = {};
+    const handlers = {};
+    const streams = {};
+    const listeners = []; // 事件订阅
+    const subCounts = {}; // 每个 pattern 的订阅数，首次订阅、全部取消时通知 GO
+    const parts = {}; // 正在接收的消息分片
+
+    // 对外只暴露冻结的对象，且 window 上的属性不可替换
+    const mb = { expose };
+    if (!IPC_NONCE) mb.newMsg = newMsg; // 开启 nonce 校验时不对外暴露，避免其他脚本借此获取 nonce
+    define(window, JS_MB, Object.freeze(mb));
 
 
     // IPC
-    window.top[JS_IPC] = window.top[JS_IPC] || {}
-    const ipc = window.top[JS_IPC];
+    const ipc = {};
     ipc.invoke = invoke;
+    ipc.invoke.withOptions = withOptions;
+    ipc.invokeWithTimeout = invokeWithTimeout;
     ipc.sent = sent;
     ipc.handle = handle;
+    ipc.stream = stream;
+    ipc.channels = channels;
+    ipc.on = on;
+    ipc.emit = emit;
+
+    // IPC 错误，可携带错误码(code)和附加数据(data)，GO 与 JS 之间双向传递
+    class IPCError extends Error {
+        constructor(message, { code = undefined, data = undefined, stack = undefined } = {}) {
+            super(message);
+            this.name = 'IPCError';
+            this.code = code;
+            this.data = data;
+            if (stack) this.stack = stack;
+        }
 
-    class IPCError {
-        constructor(msg) {
-            this.name = 'IPC Error';
-            this.message = msg;
+        // 从 GO 传来的错误内容创建
+        static from(payload) {
+            if (payload instanceof IPCError) return payload;
+            if (typeof payload === 'string') return new IPCError(payload);
+            return new IPCError(payload.message, payload);
         }
 
         toString() {
-            return this.message;
+            return this.code ? `${this.name} [${this.code}]: ${this.message}` : `${this.name}: ${this.message}`;
+        }
+    }
+    ipc.IPCError = IPCError;
+    Object.freeze(ipc.invoke);
+    define(window, JS_IPC, Object.freeze(ipc));
+
+    // 将 JS 抛出的任意错误转为传递给 GO 的错误内容
+    function toErrorPayload(err) {
+        if (typeof err === 'object' && err !== null) {
+            return {
+                code: err.code === undefined || err.code === null ? undefined : String(err.code),
+                message: err.message || err.msg || JSON.stringify(err),
+                data: err.data,
+                stack: typeof err.stack === 'string' ? err.stack : undefined,
+            };
+        }
+        return { message: String(err) };
+    }
+
+    // 流式调用的读取端，支持 onData/onEnd/onError 回调，以及 for await...of 迭代
+    // 数据被消费后才会通知 GO 继续发送（背压）
+    class IPCStream {
+        constructor(id) {
+            this.id = id;
+            this.queue = []; // 未消费的数据
+            this.pulls = []; // 等待数据的迭代器
+            this.listeners = { data: [], end: [], error: [] };
+            this.ended = false; // 已收到结束消息
+            this.done = false; // 数据已全部消费
+            this.result = undefined;
+            this.error = null;
+            this.draining = false;
+        }
+
+        onData(cb) {
+            this.listeners.data.push(cb);
+            this._drain();
+            return this;
+        }
+
+        onEnd(cb) {
+            this.listeners.end.push(cb);
+            if (this.done && !this.error) cb(this.result);
+            return this;
+        }
+
+        onError(cb) {
+            this.listeners.error.push(cb);
+            if (this.done && this.error) cb(this.error);
+            return this;
+        }
+
+        // 取消流，GO handler 的 ctx 将被取消
+        cancel() {
+            if (this.ended) return;
+            toGO(newMsg({ replyId: this.id, type: 'cancel' }));
+            this.queue = [];
+            this._end(undefined, new IPCError('IPC stream 已取消', { code: 'E_CANCELED' }));
+        }
+
+        [Symbol.asyncIterator]() {
+            return {
+                next: () => new Promise((resolve, reject) => {
+                    this.pulls.push({ resolve, reject });
+                    this._drain();
+                }),
+                return: () => {
+                    this.cancel();
+                    return Promise.resolve({ value: undefined, done: true });
+                },
+            };
+        }
+
+        _push(data) {
+            this.queue.push(data);
+            this._drain();
+        }
+
+        _end(result, error) {
+            if (this.ended) return;
+            this.ended = true;
+            this.result = result;
+            this.error = error || null;
+            this._drain();
+        }
+
+        async _drain() {
+            if (this.draining) return;
+            this.draining = true;
+            try {
+                while (this.queue.length > 0 && (this.pulls.length > 0 || this.listeners.data.length > 0)) {
+                    const data = this.queue.shift();
+                    if (this.pulls.length > 0) {
+                        this.pulls.shift().resolve({ value: data, done: false });
+                    } else {
+                        try {
+                            for (const cb of this.listeners.data) await Promise.resolve(cb(data));
+                        } catch (err) {
+                            console.error(err);
+                            this.cancel();
+                            break;
+                        }
+                    }
+                    if (!this.ended) toGO(newMsg({ replyId: this.id, type: 'ack' })); // 已消费，通知 GO 继续发送
+                }
+                if (this.ended && this.queue.length === 0) this._finish();
+            } finally {
+                this.draining = false;
+            }
+        }
+
+        _finish() {
+            // 结束后仍可能有迭代器在等待数据
+            const pulls = this.pulls;
+            this.pulls = [];
+            if (this.error) {
+                pulls.forEach(p => p.reject(this.error));
+            } else {
+                pulls.forEach(p => p.resolve({ value: undefined, done: true }));
+            }
+
+            if (this.done) return;
+            this.done = true;
+            delete streams[this.id];
+            if (this.error) {
+                this.listeners.error.forEach(cb => cb(this.error));
+            } else {
+                this.listeners.end.forEach(cb => cb(this.result));
+            }
         }
     }
 
     // GO 调用 (JS预留函数)
-    window.top[JS_GO2JS] = (msgTxt) => {
-        const msg = JSON.parse(msgTxt);
+    // GO 通常调用主 frame 的，指定 frame 调用时（如 CallJsFuncInFrame）调用该 frame 的，均由 dispatch 分派
+    // win 为收到消息的 frame 的 window，作为 handler 的 this，子 frame 转交时传入
+    define(window, JS_GO2JS, (msgTxt, win = window) => dispatch(msgTxt, win));
+
+    // 定义不可替换、不可删除的属性
+    function define(target, name, value) {
+        Object.defineProperty(target, name, { value, writable: false, enumerable: false, configurable: false });
+    }
+
+    // 分派 GO 发来的消息，win 为收到消息的 frame 的 window，作为 handler 的 this
+    // 开启合并发送时（见 WithIPCBatch），参数为消息数组，按顺序分派
+    function dispatch(msgTxt, win) {
+        if (Array.isArray(msgTxt)) {
+            for (const txt of msgTxt) {
+                try {
+                    dispatch(txt, win);
+                } catch (err) {
+                    console.error(err);
+                }
+            }
+            return
+        }
+
+        const msg = JSON.parse(msgTxt, reviveBinary);
+        if (msg.type === 'part') {
+            const data = receivePart(msg);
+            if (data !== undefined) dispatch(data, win);
+            return
+        }
+        if (msg.type === 'event') {
+            dispatchEvent(msg.channel, (msg.args || [])[0])
+            return
+        }
         if (msg.replyId) {
+            if (msg.type === 'chunk' || msg.type === 'end') {
+                handleStream(msg)
+                return
+            }
             handleReply(msg)
             return
         }
 
-        handleChannel(msg)
+        handleChannel(msg, win)
         return
-    };
+    }
 
     // JS调用 (GO预埋点)
-    const toGO = (msg) => window.top[JS_JS2GO](JSON.stringify(msg))
-    const registerHandlerToGo = window.top[JS_REGISTER_HANDLER]
+    const toGO = (msg) => sendParts(JSON.stringify(msg, replaceBinary))
+
+    IPC_SERVICES.forEach(service => expose(service));
 
     // 注册 callJsFunc (仅 JS 端)
+    // fn 可为以 . 分隔的路径，如 app.store.dispatch，调用时 this 为 app.store；从收到消息的 frame 的 window 开始查找
     ipc.handle('callJsFunc', async function (fn, ...args) {
-        const func = window.top[fn]
-        if (!func) {
-            return new Error(`JS function ${fn} not found!`)
-        }
-        return await func(...args)
+        const [self, func] = resolveFunc(this || window.top, fn)
+        return await func.apply(self, args)
     }, true)
 
+    function resolveFunc(root, path) {
+        const segments = String(path).split('.');
+        let self = root;
+        let target = root;
+        for (let i = 0; i < segments.length; i++) {
+            self = target;
+            target = target[segments[i]];
+            if (target === undefined || target === null) {
+                const missing = segments.slice(0, i + 1).join('.');
+                throw new IPCError(`JS function ${path} not found: ${missing} is ${target}`, { code: 'E_NOT_FOUND', data: { path, segment: segments[i] } })
+            }
+        }
+        if (typeof target !== 'function') {
+            throw new IPCError(`JS function ${path} not found: ${path} is not a function`, { code: 'E_NOT_FOUND', data: { path, segment: segments[segments.length - 1] } })
+        }
+        return [self, target];
+    }
+
     function randStr(len = 8) {
         let characters = 'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789';
         let randomString = '';
@@ -67,14 +294,86 @@
         }
         return randomString;
     }
-    function newMsg({ id = '', replyId = '', channel = '', args = [], result = undefined, error = undefined }) {
-        return { id, replyId, channel, args, result, error }
+    // ArrayBuffer、TypedArray、DataView 封装为 base64，GO 端还原为 []byte
+    function replaceBinary(key, value) {
+        if (value === null || typeof value !== 'object') return value;
+        if (ArrayBuffer.isView(value)) {
+            return { [BINARY_KEY]: toBase64(new Uint8Array(value.buffer, value.byteOffset, value.byteLength)) };
+        }
+        if (Object.prototype.toString.call(value) === '[object ArrayBuffer]') {
+            return { [BINARY_KEY]: toBase64(new Uint8Array(value)) };
+        }
+        return value;
     }
 
-    function withTimeout(promise, ms = 10000) {
+    // GO 传来的二进制数据还原为 Uint8Array
+    function reviveBinary(key, value) {
+        if (value === null || typeof value !== 'object' || Array.isArray(value)) return value;
+        const keys = Object.keys(value);
+        if (keys.length === 1 && keys[0] === BINARY_KEY && typeof value[BINARY_KEY] === 'string') {
+            return fromBase64(value[BINARY_KEY]);
+        }
+        return value;
+    }
+
+    function toBase64(bytes) {
+        let binary = '';
+        for (let i = 0; i < bytes.length; i += 0x8000) {
+            binary += String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000));
+        }
+        return btoa(binary);
+    }
+
+    function fromBase64(str) {
+        const binary = atob(str);
+        const bytes = new Uint8Array(binary.length);
+        for (let i = 0; i < binary.length; i++) bytes[i] = binary.charCodeAt(i);
+        return bytes;
+    }
+
+    // 超过最大长度的消息分片发送，避免单次传递过大的字符串（按字符数计算，不会切断代理对）
+    function sendParts(txt) {
+        if (!(IPC_MAX_MESSAGE_SIZE > 0) || txt.length <= IPC_MAX_MESSAGE_SIZE) {
+            js2go(txt);
+            return;
+        }
+
+        const chunks = [];
+        for (let start = 0; start < txt.length;) {
+            let end = Math.min(start + IPC_MAX_MESSAGE_SIZE, txt.length);
+            const code = txt.charCodeAt(end - 1);
+            if (end < txt.length && end - start > 1 && code >= 0xD800 && code <= 0xDBFF) end--; // 高位代理留到下一片
+            chunks.push(txt.slice(start, end));
+            start = end;
+        }
+
+        const id = randStr();
+        chunks.forEach((data, index) => {
+            js2go(JSON.stringify(newMsg({ id, type: 'part', part: { index, total: chunks.length, data } })));
+        });
+    }
+
+    // 收到分片，收齐后返回完整的消息
+    function receivePart(msg) {
+        const { id, part } = msg;
+        if (!id || !part || !(part.total > 0)) return undefined;
+        const buf = parts[id] = parts[id] || { chunks: new Array(part.total), received: 0 };
+        if (buf.chunks[part.index] === undefined) buf.received++;
+        buf.chunks[part.index] = part.data;
+        if (buf.received < part.total) return undefined;
+        delete parts[id];
+        return buf.chunks.join('');
+    }
+
+    function newMsg({ id = '', replyId = '', channel = '', args = [], result = undefined, error = undefined, type = undefined, timeout = undefined, part = undefined }) {
+        return { id, replyId, channel, args, result, error, type, timeout, part, nonce: IPC_NONCE || undefined }
+    }
+
+    function withTimeout(promise, ms = IPC_TIMEOUT) {
+        if (!(ms > 0)) return promise; // 不超时
         let timer;
         const timeout = new Promise((_, reject) => {
-            timer = setTimeout(() => reject(new Error('等待IPC Handler返回处理结果超时。')), ms);
+            timer = setTimeout(() => reject(new IPCError('等待IPC Handler返回处理结果超时。', { code: 'E_TIMEOUT' })), ms);
         });
 
         return Promise.race([promise, timeout]).finally(() => clearTimeout(timer))
@@ -83,46 +382,95 @@
     // 返回值
     function handleReply(msg) {
         if (!msg.replyId) return;
-        const p = mb.replyWaiting[msg.replyId]
+        const p = replyWaiting[msg.replyId]
         if (!p) return;
         if (msg.error) {
-            p.reject(new IPCError(msg.error))
+            p.reject(IPCError.from(msg.error))
             return;
         }
         p.resolve(msg.result)
     }
 
+    // 流数据
+    function handleStream(msg) {
+        const s = streams[msg.replyId];
+        if (!s) return;
+        if (msg.type === 'chunk') {
+            s._push(msg.result);
+            return;
+        }
+        s._end(msg.result, msg.error ? IPCError.from(msg.error) : null);
+    }
+
     // 执行handler。（GO 调用此函数，用于执行对应的handler)
-    async function handleChannel(msg) {
+    async function handleChannel(msg, win) {
         const { id, channel, args = [] } = msg || {};
         if (!channel) return;
-        const handler = mb.handlers[channel];
+        const handler = handlers[channel];
         if (!handler) return;
         if (!id) return; // ! 如果 ID 为空，则无须回复
         try {
-            const res = await Promise.resolve(handler(...args)); // 支持 promise
+            const res = await Promise.resolve(handler.apply(win, args)); // 支持 promise，this 为收到消息的 frame 的 window
             toGO(newMsg({ replyId: id, channel, args, result: res })) // 返回结果
         } catch (err) {
-            // 确保 errMsg 总是一个字符串
-            let errMsg = '';
-            if (typeof err === 'object' && err !== null) {
-                errMsg = err.message || err.msg || JSON.stringify(err);
-            } else {
-                errMsg = String(err);
-            }
-            toGO(newMsg({ replyId: id, channel, args, error: errMsg })) // 返回结果
+            toGO(newMsg({ replyId: id, channel, args, error: toErrorPayload(err) })) // 返回错误
         }
     }
 
+    // 通道的超时时间，未单独设置时为默认值
+    function timeoutOf(channel) {
+        const t = CHANNEL_TIMEOUTS[channel];
+        return t === undefined ? IPC_TIMEOUT : t;
+    }
+
     // invoke 调用, 有返回值
     function invoke(channel, ...args) {
+        return invokeWithOptions({}, channel, ...args);
+    }
+
+    // 指定超时时间（毫秒）的 invoke 调用，小于等于 0 时不超时
+    function invokeWithTimeout(timeout, channel, ...args) {
+        return invokeWithOptions({ timeout }, channel, ...args);
+    }
+
+    // 返回使用指定选项的 invoke，如：ipc.invoke.withOptions({ timeout: 60000, signal: controller.signal })('export', ...args)
+    function withOptions(options = {}) {
+        return (channel, ...args) => invokeWithOptions(options, channel, ...args);
+    }
+
+    // 返回的 Promise 带有 cancel 方法，取消后 GO handler 的 ctx 将被取消，Promise 以 E_CANCELED 错误 reject
+    // 也可通过 signal（AbortSignal）取消
+    function invokeWithOptions({ timeout = undefined, signal = undefined } = {}, channel, ...args) {
         const msg = newMsg({ id: randStr(), channel, args });
-        return withTimeout(new Promise((resolve, reject) => {
-            mb.replyWaiting[msg.id] = { resolve, reject }
+        if (timeout === undefined || timeout === null) {
+            timeout = timeoutOf(channel);
+        } else {
+            msg.timeout = timeout > 0 ? timeout : -1; // 告知 GO 使用相同的超时时间
+        }
+
+        let cancel = () => { };
+        const onAbort = () => cancel();
+        const p = withTimeout(new Promise((resolve, reject) => {
+            replyWaiting[msg.id] = { resolve, reject }
+            cancel = () => {
+                if (!replyWaiting[msg.id]) return; // 已结束
+                delete replyWaiting[msg.id];
+                toGO(newMsg({ replyId: msg.id, type: 'cancel' }));
+                reject(new IPCError('IPC 调用已取消', { code: 'E_CANCELED' }));
+            };
+            if (signal && signal.aborted) {
+                reject(new IPCError('IPC 调用已取消', { code: 'E_CANCELED' }));
+                return;
+            }
             toGO(msg)
-        })).finally(() => {
-            delete mb.replyWaiting[msg.id]
+        }), timeout).finally(() => {
+            delete replyWaiting[msg.id]
+            if (signal) signal.removeEventListener('abort', onAbort);
         })
+
+        if (signal) signal.addEventListener('abort', onAbort);
+        p.cancel = () => cancel();
+        return p;
     }
 
     // sent 调用，没有返回值
@@ -131,12 +479,89 @@
         toGO(msg)
     }
 
+    // stream 调用，GO handler 持续推送数据，返回 IPCStream
+    function stream(channel, ...args) {
+        const msg = newMsg({ id: randStr(), type: 'stream', channel, args });
+        const s = new IPCStream(msg.id);
+        streams[msg.id] = s;
+        toGO(msg);
+        return s;
+    }
+
+    // 获取所有已注册的通道及其参数、返回值类型
+    function channels() {
+        return invoke(IPC_CHANNELS);
+    }
+
+    // 订阅事件，返回取消订阅的函数
+    // topic 以 . 分隔，pattern 中 * 匹配一段，** 匹配任意段，如 ipc.on('settings.*', (payload, topic) => {})
+    function on(pattern, cb) {
+        const listener = { pattern, cb };
+        listeners.push(listener);
+        subCounts[pattern] = (subCounts[pattern] || 0) + 1;
+        if (subCounts[pattern] === 1) toGO(newMsg({ type: 'sub', channel: pattern }));
+
+        let subscribed = true;
+        return () => {
+            if (!subscribed) return;
+            subscribed = false;
+            const i = listeners.indexOf(listener);
+            if (i >= 0) listeners.splice(i, 1);
+            if (--subCounts[pattern] === 0) {
+                delete subCounts[pattern];
+                toGO(newMsg({ type: 'unsub', channel: pattern }));
+            }
+        };
+    }
+
+    // 发布事件，当前页面、GO 以及其他订阅了该 topic 的页面都会收到
+    function emit(topic, payload) {
+        dispatchEvent(topic, payload);
+        toGO(newMsg({ type: 'event', channel: topic, args: [payload] }));
+    }
+
+    function dispatchEvent(topic, payload) {
+        for (const listener of listeners.slice()) {
+            if (!matchTopic(listener.pattern, topic)) continue;
+            try {
+                listener.cb(payload, topic);
+            } catch (err) {
+                console.error(err);
+            }
+        }
+    }
+
+    // 与 GO 端的 MatchTopic 一致
+    function matchTopic(pattern, topic) {
+        const match = (p, t) => {
+            if (p.length === 0) return t.length === 0;
+            if (p[0] === '**') {
+                for (let i = 0; i <= t.length; i++) {
+                    if (match(p.slice(1), t.slice(i))) return true;
+                }
+                return false;
+            }
+            if (t.length === 0 || (p[0] !== '*' && p[0] !== t[0])) return false;
+            return match(p.slice(1), t.slice(1));
+        };
+        return match(pattern.split('.'), topic.split('.'));
+    }
+
+    // 安装 GO 对象的代理，如 files.list(dir) 即 ipc.invoke('files.list', dir)
+    function expose({ name, methods = [] }, target = window) {
+        const proxy = {};
+        for (const { name: method, channel, stream: isStream } of methods) {
+            proxy[method] = isStream
+                ? (...args) => stream(channel, ...args)
+                : (...args) => invoke(channel, ...args);
+        }
+        target[name] = Object.freeze(proxy);
+    }
+
     // 声明handler
     function handle(channel, handler, onlyInJS = false) {
-        window.top[JS_MB] = window.top[JS_MB] || {}
-        window.top[JS_MB]['handlers'] = window.top[JS_MB]['handlers'] || {};
-        window.top[JS_MB]['handlers'][channel] = handler; // 存入cache
-        if (!onlyInJS) registerHandlerToGo(channel)
+        handlers[channel] = handler; // 存入cache
+        if (!onlyInJS) registerHandlerToGo(channel, IPC_NONCE)
     }
 
 })();
@@ -0,0 +1,9 @@
+package blink
+
+import ipcCore "github.com/epkgs/blink/pkg/ipc"
+
+// 声明的生成见 ipcCore.Router.GenerateTypeScript，IPC 内嵌了 Router，可直接调用 GenerateTypeScript、WriteTypeScriptFile
+const (
+	ENV_DTS_OUT = ipcCore.ENV_DTS_OUT // 以 blink_dts 标签构建时，KeepRunning 将声明写入此环境变量指定的文件并退出程序
+	TAG_DTS     = ipcCore.TAG_DTS     // cmd/blink-dts 构建目标程序时使用的标签
+)
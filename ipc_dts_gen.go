@@ -0,0 +1,25 @@
+//go:build blink_dts
+
+package blink
+
+import (
+	"os"
+
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 由 cmd/blink-dts 启动时，生成声明文件后直接退出
+func (ipc *IPC) exitIfGeneratingDTS() {
+	out := os.Getenv(ENV_DTS_OUT)
+	if out == "" {
+		return
+	}
+
+	if err := ipc.WriteTypeScriptFile(out); err != nil {
+		log.Error("生成 TypeScript 声明失败: %s", err.Error())
+		os.Exit(1)
+	}
+
+	log.Info("已生成 TypeScript 声明: %s", out)
+	os.Exit(0)
+}
@@ -0,0 +1,6 @@
+//go:build !blink_dts
+
+package blink
+
+// 未以 blink_dts 标签构建时不生成声明，见 ENV_DTS_OUT
+func (ipc *IPC) exitIfGeneratingDTS() {}
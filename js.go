@@ -1,22 +1,40 @@
 package blink
 
 import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
 	"reflect"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 	"unsafe"
+
+	"github.com/epkgs/blink/internal/cast"
+	"github.com/epkgs/blink/internal/log"
 )
 
 type BindFunctionCallback func(es JsExecState)
 
 type JS struct {
-	mb *Blink
+	mb   *Blink
+	call func(funcName string, args ...uintptr) (r1 uintptr, r2 uintptr, err error) // 调用 mb 的导出函数，测试时替换为模拟的 JS 引擎
+
+	strictArgs bool // 是否严格转换绑定函数、方法的参数，见 WithIPCLenientArgs
+
+	bindMu    sync.Mutex
+	bindings  map[uintptr]*jsBinding // 绑定到 JS 的 GO 对象，以 jsData 的地址为键
+	bindOnce  sync.Once
+	callbacks jsBindCallbacks
 }
 
 func newJS(blink *Blink) *JS {
 	js := &JS{
-		mb: blink,
+		mb:         blink,
+		call:       blink.CallFunc,
+		strictArgs: !blink.Config.GetIPCLenientArgs(),
+		bindings:   make(map[uintptr]*jsBinding),
 	}
 
 	return js
@@ -29,74 +47,69 @@ func (js *JS) bindFunction(funcName string, funcArgCount uint32, callback BindFu
 
 		return 0
 	}
-	_, _, _ = js.mb.CallFunc("wkeJsBindFunction", StringToPtr(funcName), CallbackToPtr(cb), 0, uintptr(funcArgCount))
+	_, _, _ = js.call("wkeJsBindFunction", StringToPtr(funcName), CallbackToPtr(cb), 0, uintptr(funcArgCount))
 }
 
 // 获取页面主frame的jsExecState
 func (js *JS) GlobalExec(viewHandle WkeHandle) (es JsExecState) {
 
-	ptr, _, _ := js.mb.CallFunc("wkeGlobalExec", uintptr(viewHandle))
+	ptr, _, _ := js.call("wkeGlobalExec", uintptr(viewHandle))
 
 	return JsExecState(ptr)
 }
 
 func (js *JS) GetWebView(es JsExecState) WkeHandle {
-	p, _, _ := js.mb.CallFunc("jsGetWebView", uintptr(es))
+	p, _, _ := js.call("jsGetWebView", uintptr(es))
 	return WkeHandle(p)
 }
 
 func (js *JS) TypeOf(val JsValue) JsType {
-	ptr, _, _ := js.mb.CallFunc("jsTypeOf", uintptr(val))
+	ptr, _, _ := js.call("jsTypeOf", uintptr(val))
 	return JsType(ptr)
 }
 
 func (js *JS) Undefined() JsValue {
-	r, _, _ := js.mb.CallFunc("jsUndefined")
+	r, _, _ := js.call("jsUndefined")
 	return JsValue(r)
 }
 
 func (js *JS) Int(value int32) JsValue {
-	r, _, _ := js.mb.CallFunc("jsInt", uintptr(value))
-	return JsValue(r)
-}
-
-func (js *JS) Double(value float64) JsValue {
-	r, _, _ := js.mb.CallFunc("jsDouble", uintptr(value))
+	r, _, _ := js.call("jsInt", uintptr(value))
 	return JsValue(r)
 }
 
 func (js *JS) Boolean(value bool) JsValue {
-	r, _, _ := js.mb.CallFunc("jsBoolean", BoolToPtr(value))
+	r, _, _ := js.call("jsBoolean", BoolToPtr(value))
 	return JsValue(r)
 }
 
 func (js *JS) ArgCount(es JsExecState) uint32 {
-	ptr, _, _ := js.mb.CallFunc("jsArgCount", uintptr(es))
+	ptr, _, _ := js.call("jsArgCount", uintptr(es))
 	return uint32(ptr)
 }
 
 // 判断第argIdx个参数的参数类型。argIdx从是个0开始计数的值。如果超出jsArgCount返回的值，将发生崩溃
 func (js *JS) ArgType(es JsExecState, argIdx uint32) JsType {
-	ptr, _, _ := js.mb.CallFunc("jsArgType", uintptr(es), uintptr(argIdx))
+	ptr, _, _ := js.call("jsArgType", uintptr(es), uintptr(argIdx))
 	return JsType(ptr)
 }
 
 // 获取第argIdx对应的参数的jsValue值。
 func (js *JS) Arg(es JsExecState, argIdx uint32) JsValue {
-	ptr, _, _ := js.mb.CallFunc("jsArg", uintptr(es), uintptr(argIdx))
+	ptr, _, _ := js.call("jsArg", uintptr(es), uintptr(argIdx))
 	return JsValue(ptr)
 }
 
 // str的代码会在mb内部自动被包裹在一个function(){}中。所以使用的变量会被隔离 注意：要获取返回值，请写return。
 func (js *JS) Eval(es JsExecState, str string) JsValue {
-	ptr, _, _ := js.mb.CallFunc("jsEvalW", uintptr(es), StringToWCharPtr(str))
+	ptr, _, _ := js.call("jsEvalW", uintptr(es), StringToWCharPtr(str))
 	return JsValue(ptr)
 }
 
 // 如果object是个js的object，则获取prop指定的属性。如果object不是js object类型，则返回 nil
 func (js *JS) Get(es JsExecState, object JsValue, prop string) JsValue {
 
-	ptr, _, _ := js.mb.CallFunc("jsGet", uintptr(es), uintptr(object), StringToPtr(prop))
+	ptr, _, _ := js.call("jsGet", uintptr(es), uintptr(object), StringToPtr(prop))
 
 	return JsValue(ptr)
 }
@@ -104,13 +117,13 @@ func (js *JS) Get(es JsExecState, object JsValue, prop string) JsValue {
 // 设置object的属性
 func (js *JS) Set(es JsExecState, object JsValue, prop string, value JsValue) {
 
-	_, _, _ = js.mb.CallFunc("jsSet", uintptr(es), uintptr(object), StringToPtr(prop), uintptr(value))
+	_, _, _ = js.call("jsSet", uintptr(es), uintptr(object), StringToPtr(prop), uintptr(value))
 }
 
 // 获取window上的属性
 func (js *JS) GetGlobal(es JsExecState, prop string) JsValue {
 
-	ptr, _, _ := js.mb.CallFunc("jsGetGlobal", uintptr(es), StringToPtr(prop))
+	ptr, _, _ := js.call("jsGetGlobal", uintptr(es), StringToPtr(prop))
 
 	return JsValue(ptr)
 }
@@ -118,25 +131,25 @@ func (js *JS) GetGlobal(es JsExecState, prop string) JsValue {
 // 设置window上的属性
 func (js *JS) SetGlobal(es JsExecState, prop string, value JsValue) {
 
-	_, _, _ = js.mb.CallFunc("jsSetGlobal", uintptr(es), StringToPtr(prop), uintptr(value))
+	_, _, _ = js.call("jsSetGlobal", uintptr(es), StringToPtr(prop), uintptr(value))
 }
 
 // 设置js arrary的第index个成员的值，object必须是js array才有用，否则会返回nil
 func (js *JS) GetAt(es JsExecState, object JsValue, index uint32) JsValue {
-	p, _, _ := js.mb.CallFunc("jsGetAt", uintptr(es), uintptr(object), uintptr(index))
+	p, _, _ := js.call("jsGetAt", uintptr(es), uintptr(object), uintptr(index))
 	return JsValue(p)
 }
 
 // 设置js arrary的第index个成员的值，object必须是js array才有用。
 func (js *JS) SetAt(es JsExecState, object JsValue, index uint32, value JsValue) {
 
-	_, _, _ = js.mb.CallFunc("jsSetAt", uintptr(es), uintptr(object), uintptr(index), uintptr(value))
+	_, _, _ = js.call("jsSetAt", uintptr(es), uintptr(object), uintptr(index), uintptr(value))
 }
 
 // 获取object有哪些key
 func (js *JS) GetKeys(es JsExecState, object JsValue) []string {
 
-	p, _, _ := js.mb.CallFunc("jsGetKeys", uintptr(es), uintptr(object))
+	p, _, _ := js.call("jsGetKeys", uintptr(es), uintptr(object))
 
 	keys := *((*JsKeys)(unsafe.Pointer(p)))
 
@@ -149,48 +162,67 @@ func (js *JS) GetKeys(es JsExecState, object JsValue) []string {
 }
 
 func (js *JS) String(es JsExecState, value string) JsValue {
-	r, _, _ := js.mb.CallFunc("jsString", uintptr(es), StringToPtr(value))
+	r, _, _ := js.call("jsString", uintptr(es), StringToPtr(value))
 	return JsValue(r)
 }
 func (js *JS) EmptyArray(es JsExecState) JsValue {
-	r, _, _ := js.mb.CallFunc("jsEmptyArray", uintptr(es))
+	r, _, _ := js.call("jsEmptyArray", uintptr(es))
 	return JsValue(r)
 }
 func (js *JS) EmptyObject(es JsExecState) JsValue {
-	r, _, _ := js.mb.CallFunc("jsEmptyObject", uintptr(es))
+	r, _, _ := js.call("jsEmptyObject", uintptr(es))
 	return JsValue(r)
 }
 
 // 获取js arrary的长度，object必须是js array才有用。
 func (js *JS) GetLength(es JsExecState, object JsValue) int {
-	p, _, _ := js.mb.CallFunc("jsGetLength", uintptr(es), uintptr(object))
+	p, _, _ := js.call("jsGetLength", uintptr(es), uintptr(object))
 	return int(p)
 }
 
 func (js *JS) SetLength(es JsExecState, object JsValue, length uint32) {
-	_, _, _ = js.mb.CallFunc("jsSetLength", uintptr(es), uintptr(object), uintptr(length))
-}
-
-func (js *JS) ToDouble(es JsExecState, value JsValue) float64 {
-	p, _, _ := js.mb.CallFunc("jsToDouble", uintptr(es), uintptr(value))
-	return float64(p)
+	_, _, _ = js.call("jsSetLength", uintptr(es), uintptr(object), uintptr(length))
 }
 
 func (js *JS) ToBoolean(es JsExecState, value JsValue) bool {
-	p, _, _ := js.mb.CallFunc("jsToBoolean", uintptr(es), uintptr(value))
+	p, _, _ := js.call("jsToBoolean", uintptr(es), uintptr(value))
 	return p != 0
 }
 
 func (js *JS) ToTempString(es JsExecState, value JsValue) string {
-	p, _, _ := js.mb.CallFunc("jsToTempString", uintptr(es), uintptr(value))
+	p, _, _ := js.call("jsToTempString", uintptr(es), uintptr(value))
 	return PtrToString(p)
 }
 
 func (js *JS) ToString(es JsExecState, value JsValue) string {
-	p, _, _ := js.mb.CallFunc("jsToString", uintptr(es), uintptr(value))
+	p, _, _ := js.call("jsToString", uintptr(es), uintptr(value))
 	return PtrToString(p)
 }
 
+// 以 jsData 创建 JS 对象，读写属性时回调 data 中的 PropertyGet、PropertySet
+func (js *JS) Object(es JsExecState, data *JsData) JsValue {
+	r, _, _ := js.call("jsObject", uintptr(es), uintptr(unsafe.Pointer(data)))
+	return JsValue(r)
+}
+
+// 以 jsData 创建 JS 函数，调用时回调 data 中的 CallAsFunction
+func (js *JS) Function(es JsExecState, data *JsData) JsValue {
+	r, _, _ := js.call("jsFunction", uintptr(es), uintptr(unsafe.Pointer(data)))
+	return JsValue(r)
+}
+
+// 获取由 Object、Function 创建的 JS 值对应的 jsData，其他值返回 nil
+func (js *JS) GetData(es JsExecState, object JsValue) *JsData {
+	p, _, _ := js.call("jsGetData", uintptr(es), uintptr(object))
+	return AssertType[JsData](p)
+}
+
+// 在 JS 中抛出异常，返回值应作为当前回调的返回值
+func (js *JS) ThrowException(es JsExecState, message string) JsValue {
+	r, _, _ := js.call("jsThrowException", uintptr(es), StringToPtr(message))
+	return JsValue(r)
+}
+
 func (js *JS) Call(es JsExecState, fn, thisValue JsValue, args []JsValue) JsValue {
 	var ptr = uintptr(0)
 	l := len(args)
@@ -198,114 +230,392 @@ func (js *JS) Call(es JsExecState, fn, thisValue JsValue, args []JsValue) JsValu
 		ptr = uintptr(unsafe.Pointer(&args[0]))
 	}
 
-	r, _, _ := js.mb.CallFunc("jsCall", uintptr(es), uintptr(fn), uintptr(thisValue), ptr, uintptr(l))
+	r, _, _ := js.call("jsCall", uintptr(es), uintptr(fn), uintptr(thisValue), ptr, uintptr(l))
 	return JsValue(r)
 }
 
+// GO 值转换为 JS 值，出错时记录日志并返回 undefined，需要错误信息时使用 ToJsValueE
 func (js *JS) ToJsValue(es JsExecState, value interface{}) JsValue {
-	if value == nil {
+	v, err := js.ToJsValueE(es, value)
+	if err != nil {
+		log.Error(err.Error())
 		return js.Undefined()
 	}
-	switch val := value.(type) {
-	case int:
-		return js.Int(int32(val))
-	case int8:
-		return js.Int(int32(val))
-	case int16:
-		return js.Int(int32(val))
-	case int32:
-		return js.Int(val)
-	case int64:
-		return js.Double(float64(val))
-	case uint:
-		return js.Int(int32(val))
-	case uint8:
-		return js.Int(int32(val))
-	case uint16:
-		return js.Int(int32(val))
-	case uint32:
-		return js.Int(int32(val))
-	case uint64:
-		return js.Double(float64(val))
-	case float32:
-		return js.Double(float64(val))
-	case float64:
-		return js.Double(val)
-	case bool:
-		return js.Boolean(val)
-	case string:
-		return js.String(es, val)
-	case time.Time:
-		return js.Double(float64(val.Unix()))
-	default:
-		break
+	return v
+}
+
+// JS 值转换为 GO 值，出错时记录日志并返回 nil，需要错误信息时使用 ToGoValueE
+func (js *JS) ToGoValue(es JsExecState, value JsValue) interface{} {
+	v, err := js.ToGoValueE(es, value)
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	return v
+}
+
+// JS 与 GO 之间转换值时，遇到函数的处理方式
+type JsFuncPolicy int
+
+const (
+	JS_FUNC_OMIT   JsFuncPolicy = iota // 忽略：作为对象属性时跳过，其他位置转为 nil/undefined
+	JS_FUNC_ERROR                      // 返回错误
+	JS_FUNC_HANDLE                     // JS 函数转为可调用的 *JsFunc（仅 ToGoValueE）
+)
+
+// 超出 JS 安全整数范围（±2^53-1）的 int64、uint64 的转换方式
+type JsLargeIntPolicy int
+
+const (
+	JS_LARGE_INT_STRING JsLargeIntPolicy = iota // 十进制字符串
+	JS_LARGE_INT_BIGINT                         // BigInt，不支持 BigInt 的内核降级为字符串
+	JS_LARGE_INT_NUMBER                         // number，会损失精度
+)
+
+// time.Time 的转换方式
+type JsTimePolicy int
+
+const (
+	JS_TIME_MILLIS JsTimePolicy = iota // 毫秒时间戳，可直接用于 new Date(ms)
+	JS_TIME_ISO                        // ISO 8601 字符串（UTC），同 Date.prototype.toISOString
+)
+
+// 默认的最大嵌套深度，超过时视为循环引用
+const DEFAULT_JS_MAX_DEPTH = 64
+
+// JS 中可精确表示的最大整数 2^53-1
+const JS_MAX_SAFE_INTEGER = 1<<53 - 1
+
+type jsConvertOptions struct {
+	maxDepth  int
+	funcs     JsFuncPolicy
+	largeInts JsLargeIntPolicy
+	times     JsTimePolicy
+}
+
+type JsConvertOption func(*jsConvertOptions)
+
+// 最大嵌套深度，超过时返回错误，默认为 DEFAULT_JS_MAX_DEPTH
+func WithJsMaxDepth(depth int) JsConvertOption {
+	return func(o *jsConvertOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// 遇到函数时的处理方式，默认为 JS_FUNC_OMIT
+func WithJsFuncPolicy(policy JsFuncPolicy) JsConvertOption {
+	return func(o *jsConvertOptions) {
+		o.funcs = policy
+	}
+}
+
+// 超出安全整数范围的 int64、uint64 的转换方式，默认为 JS_LARGE_INT_STRING
+func WithJsLargeIntPolicy(policy JsLargeIntPolicy) JsConvertOption {
+	return func(o *jsConvertOptions) {
+		o.largeInts = policy
+	}
+}
+
+// time.Time 的转换方式，默认为 JS_TIME_MILLIS
+func WithJsTimePolicy(policy JsTimePolicy) JsConvertOption {
+	return func(o *jsConvertOptions) {
+		o.times = policy
+	}
+}
+
+func newJsConvertOptions(opts []JsConvertOption) *jsConvertOptions {
+	o := &jsConvertOptions{maxDepth: DEFAULT_JS_MAX_DEPTH, funcs: JS_FUNC_OMIT}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// 值转换出错，Path 为出错的位置，如 $.items[3].handler
+type JsConvertError struct {
+	Path   string
+	Reason string
+}
+
+func (e *JsConvertError) Error() string {
+	return fmt.Sprintf("JS 值转换出错 %s: %s", e.Path, e.Reason)
+}
+
+// JS 函数，仅在取得它的 JsExecState 有效期间（通常为本次回调内）可调用
+type JsFunc struct {
+	js   *JS
+	es   JsExecState
+	fn   JsValue
+	this JsValue
+	Path string // 在转换的值中的位置
+}
+
+// 调用 JS 函数，参数与返回值按 ToJsValueE/ToGoValueE 转换
+func (f *JsFunc) Call(args ...interface{}) (interface{}, error) {
+	jsArgs := make([]JsValue, len(args))
+	for i, arg := range args {
+		v, err := f.js.ToJsValueE(f.es, arg)
+		if err != nil {
+			return nil, err
+		}
+		jsArgs[i] = v
 	}
-	rt := reflect.TypeOf(value)
-	rv := reflect.ValueOf(value)
+
+	return f.js.ToGoValueE(f.es, f.js.Call(f.es, f.fn, f.this, jsArgs))
+}
+
+// GO 值转换为 JS 值，结构体按 json tag 确定字段名称，实现了 json.Marshaler、encoding.TextMarshaler 的值由其自行序列化
+//
+// 超出安全整数范围的整数按 WithJsLargeIntPolicy 转换，time.Time 按 WithJsTimePolicy 转换
+//
+// 不支持的类型（chan、complex 等）、超过最大深度（含循环引用）时返回 *JsConvertError，函数按 WithJsFuncPolicy 处理
+func (js *JS) ToJsValueE(es JsExecState, value interface{}, opts ...JsConvertOption) (JsValue, error) {
+	c := &toJsConverter{js: js, es: es, opts: newJsConvertOptions(opts), visiting: map[uintptr]bool{}}
+	v, _, err := c.convert(reflect.ValueOf(value), "$", 0)
+	return v, err
+}
+
+type toJsConverter struct {
+	js       *JS
+	es       JsExecState
+	opts     *jsConvertOptions
+	visiting map[uintptr]bool // 当前路径上的指针、map、slice，用于检测循环引用
+}
+
+// 返回的 ok 为 false 时表示该值被忽略
+func (c *toJsConverter) convert(rv reflect.Value, path string, depth int) (v JsValue, ok bool, err error) {
+	js, es := c.js, c.es
+
+	if !rv.IsValid() {
+		return js.Undefined(), true, nil
+	}
+
+	if depth > c.opts.maxDepth {
+		return 0, false, &JsConvertError{Path: path, Reason: fmt.Sprintf("超过最大深度 %d，可能存在循环引用", c.opts.maxDepth)}
+	}
+
+	// 先取出接口中的值：其中为 nil 指针时，不能调用值接收者的 MarshalJSON 等方法
+	if rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return js.Undefined(), true, nil
+		}
+		return c.convert(rv.Elem(), path, depth)
+	}
+
+	// 实现了 json.Marshaler、encoding.TextMarshaler 的值由其自行序列化
+	if rv.CanInterface() && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		switch val := rv.Interface().(type) {
+		case time.Time:
+			if c.opts.times == JS_TIME_ISO {
+				return js.String(es, val.UTC().Format("2006-01-02T15:04:05.000Z07:00")), true, nil
+			}
+			return js.Double(float64(val.UnixMilli())), true, nil
+		case *time.Time:
+			// *time.Time 同样实现了 json.Marshaler，须按 WithJsTimePolicy 转换
+			return c.convert(rv.Elem(), path, depth)
+		case json.Marshaler:
+			data, err := val.MarshalJSON()
+			if err != nil {
+				return 0, false, &JsConvertError{Path: path, Reason: err.Error()}
+			}
+			var decoded interface{}
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				return 0, false, &JsConvertError{Path: path, Reason: err.Error()}
+			}
+			return c.convert(reflect.ValueOf(decoded), path, depth)
+		case encoding.TextMarshaler:
+			text, err := val.MarshalText()
+			if err != nil {
+				return 0, false, &JsConvertError{Path: path, Reason: err.Error()}
+			}
+			return js.String(es, string(text)), true, nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		switch {
+		case n >= math.MinInt32 && n <= math.MaxInt32:
+			return js.Int(int32(n)), true, nil
+		case n >= -JS_MAX_SAFE_INTEGER && n <= JS_MAX_SAFE_INTEGER:
+			return js.Double(float64(n)), true, nil
+		}
+		return c.largeInt(strconv.FormatInt(n, 10), float64(n)), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := rv.Uint()
+		switch {
+		case n <= math.MaxInt32:
+			return js.Int(int32(n)), true, nil
+		case n <= JS_MAX_SAFE_INTEGER:
+			return js.Double(float64(n)), true, nil
+		}
+		return c.largeInt(strconv.FormatUint(n, 10), float64(n)), true, nil
+	case reflect.Float32, reflect.Float64:
+		return js.Double(rv.Float()), true, nil
+	case reflect.Bool:
+		return js.Boolean(rv.Bool()), true, nil
+	case reflect.String:
+		return js.String(es, rv.String()), true, nil
+	}
+
+	// 循环引用检测
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return js.Undefined(), true, nil
+		}
+		ptr := rv.Pointer()
+		if rv.Kind() != reflect.Slice || rv.Len() > 0 {
+			if c.visiting[ptr] {
+				return 0, false, &JsConvertError{Path: path, Reason: "存在循环引用"}
+			}
+			c.visiting[ptr] = true
+			defer delete(c.visiting, ptr)
+		}
+	}
+
 	switch rv.Kind() {
+	case reflect.Ptr:
+		return c.convert(rv.Elem(), path, depth)
+
 	case reflect.Slice, reflect.Array:
 		length := rv.Len()
 		arr := js.EmptyArray(es)
 		js.SetLength(es, arr, uint32(length))
 		for i := 0; i < length; i++ {
-			v := js.ToJsValue(es, rv.Index(i).Interface())
+			v, ok, err := c.convert(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), depth+1)
+			if err != nil {
+				return 0, false, err
+			}
+			if !ok {
+				v = js.Undefined()
+			}
 			js.SetAt(es, arr, uint32(i), v)
 		}
-		return arr
+		return arr, true, nil
+
 	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return 0, false, &JsConvertError{Path: path, Reason: "不支持的 map 键类型 " + rv.Type().Key().String()}
+		}
 		obj := js.EmptyObject(es)
 		kv := rv.MapRange()
-		for kv.Next() && kv.Key().Kind() == reflect.String {
-			k := kv.Key().Interface().(string)
-			v := js.ToJsValue(es, kv.Value().Interface())
-			js.Set(es, obj, k, v)
+		for kv.Next() {
+			k := kv.Key().String()
+			v, ok, err := c.convert(kv.Value(), path+"."+k, depth+1)
+			if err != nil {
+				return 0, false, err
+			}
+			if ok {
+				js.Set(es, obj, k, v)
+			}
 		}
-		return obj
+		return obj, true, nil
+
 	case reflect.Struct:
+		// 按 json tag 确定字段名称，支持 `-`、omitempty、string 及内嵌结构体
 		obj := js.EmptyObject(es)
-		for i := 0; i < rv.NumField(); i++ {
-			f := rt.Field(i)
-			if strings.ToUpper(f.Name)[0] == f.Name[0] {
-				fname := rt.Field(i).Name
-				fvalue := rv.Field(i).Interface()
-				v := js.ToJsValue(es, fvalue)
-				js.Set(es, obj, fname, v)
+		for _, f := range cast.JSONFields(rv.Type()) {
+			fv, exist := cast.FieldByIndex(rv, f.Index)
+			if !exist || (f.OmitEmpty && cast.IsEmptyValue(fv)) {
+				continue
+			}
+			if f.Quoted && fv.CanInterface() {
+				data, _ := json.Marshal(fv.Interface())
+				js.Set(es, obj, f.Name, js.String(es, string(data)))
+				continue
+			}
+			v, ok, err := c.convert(fv, path+"."+f.Name, depth+1)
+			if err != nil {
+				return 0, false, err
 			}
+			if ok {
+				js.Set(es, obj, f.Name, v)
+			}
+		}
+		return obj, true, nil
+
+	case reflect.Func:
+		if c.opts.funcs == JS_FUNC_ERROR {
+			return 0, false, &JsConvertError{Path: path, Reason: "不支持转换函数 " + rv.Type().String()}
 		}
-		return obj
+		return 0, false, nil
 	}
-	// TODO: 移除 panic，应该使用返回 error
-	panic("不支持的go类型：" + rv.Kind().String() + "(" + rv.Type().String() + ")")
+
+	return 0, false, &JsConvertError{Path: path, Reason: "不支持的 GO 类型 " + rv.Type().String()}
 }
 
-func (js *JS) ToGoValue(es JsExecState, value JsValue) interface{} {
-	switch js.TypeOf(value) {
+// 超出安全整数范围的整数，按 WithJsLargeIntPolicy 转换
+func (c *toJsConverter) largeInt(digits string, lossy float64) JsValue {
+	switch c.opts.largeInts {
+	case JS_LARGE_INT_NUMBER:
+		return c.js.Double(lossy)
+	case JS_LARGE_INT_BIGINT:
+		return c.js.Eval(c.es, fmt.Sprintf("return typeof BigInt === 'function' ? BigInt(%q) : %q;", digits, digits))
+	default:
+		return c.js.String(c.es, digits)
+	}
+}
+
+// JS 值转换为 GO 值：数字为 float64，数组为 []interface{}，对象为 map[string]interface{}
+//
+// 不支持的类型（如 Symbol）、超过最大深度（含循环引用）时返回 *JsConvertError，函数按 WithJsFuncPolicy 处理
+func (js *JS) ToGoValueE(es JsExecState, value JsValue, opts ...JsConvertOption) (interface{}, error) {
+	o := newJsConvertOptions(opts)
+	v, _, err := js.toGoValue(es, value, 0, "$", 0, o)
+	return v, err
+}
+
+// this 为 value 所属的对象，用于 JsFunc；返回的 ok 为 false 时表示该值被忽略
+func (js *JS) toGoValue(es JsExecState, value, this JsValue, path string, depth int, o *jsConvertOptions) (v interface{}, ok bool, err error) {
+
+	if depth > o.maxDepth {
+		return nil, false, &JsConvertError{Path: path, Reason: fmt.Sprintf("超过最大深度 %d，可能存在循环引用", o.maxDepth)}
+	}
+
+	switch typ := js.TypeOf(value); typ {
 	case JsType_NULL, JsType_UNDEFINED:
-		return nil
+		return nil, true, nil
 	case JsType_NUMBER:
-		return js.ToDouble(es, value)
+		return js.ToDouble(es, value), true, nil
 	case JsType_BOOLEAN:
-		return js.ToBoolean(es, value)
+		return js.ToBoolean(es, value), true, nil
 	case JsType_STRING:
-		return js.ToTempString(es, value)
+		return js.ToTempString(es, value), true, nil
 	case JsType_ARRAY:
 		length := js.GetLength(es, value)
 		ps := make([]interface{}, length)
 		for i := 0; i < length; i++ {
-			v := js.GetAt(es, value, uint32(i))
-			ps[i] = js.ToGoValue(es, v)
+			item, _, err := js.toGoValue(es, js.GetAt(es, value, uint32(i)), value, fmt.Sprintf("%s[%d]", path, i), depth+1, o)
+			if err != nil {
+				return nil, false, err
+			}
+			ps[i] = item
 		}
-		return ps
+		return ps, true, nil
 	case JsType_OBJECT:
 		ps := make(map[string]interface{})
-		keys := js.GetKeys(es, value)
-		for _, k := range keys {
-			v := js.Get(es, value, k)
-			ps[k] = js.ToGoValue(es, v)
+		for _, k := range js.GetKeys(es, value) {
+			item, ok, err := js.toGoValue(es, js.Get(es, value, k), value, path+"."+k, depth+1, o)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				ps[k] = item
+			}
+		}
+		return ps, true, nil
+	case JsType_FUNCTION:
+		switch o.funcs {
+		case JS_FUNC_HANDLE:
+			return &JsFunc{js: js, es: es, fn: value, this: this, Path: path}, true, nil
+		case JS_FUNC_ERROR:
+			return nil, false, &JsConvertError{Path: path, Reason: "不支持转换函数"}
+		default:
+			return nil, false, nil
 		}
-		return ps
 	default:
-		// TODO: 移除 panic，应该使用返回 error
-		panic("不支持的js类型：" + strconv.Itoa(int(value)))
+		return nil, false, &JsConvertError{Path: path, Reason: "不支持的 JS 类型 " + strconv.Itoa(int(typ))}
 	}
 }
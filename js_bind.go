@@ -0,0 +1,341 @@
+package blink
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/epkgs/blink/internal/cast"
+)
+
+// 绑定到 JS 的 GO 对象或其方法
+type jsBinding struct {
+	data   JsData // 交给 mb 的 jsData，其地址作为查找绑定的键，需在绑定期间保持存活
+	value  reflect.Value
+	method reflect.Value // 有效时表示这是对象的方法
+	fields []cast.Field
+	locker sync.Locker
+
+	methods map[string]*jsBinding // 已创建的方法绑定，按 GO 方法名缓存
+}
+
+// mb 回调，所有绑定共用，避免重复创建 syscall callback
+type jsBindCallbacks struct {
+	getter         uintptr
+	function       uintptr
+	propertyGet    uintptr
+	propertySet    uintptr
+	finalize       uintptr
+	callAsFunction uintptr
+}
+
+// 将 GO 对象绑定为 window[name]，JS 读写属性、调用方法时同步访问该对象，适合小型的配置、状态对象
+//
+//   - obj 须为结构体指针或键为字符串的 map
+//   - 属性按 json tag 匹配字段，方法名忽略大小写匹配（如 getName 对应 GetName）
+//   - 方法的参数按 ToGoValueE 转换后，与 IPC handler 一样默认严格转换为参数类型，开启 WithIPCLenientArgs 时宽松转换
+//   - 返回值按 ToJsValueE 转换，返回的 error 及转换失败会作为 JS 异常抛出
+//   - obj 实现了 sync.Locker 时，读写属性期间会加锁；调用方法时不加锁，由方法自行加锁
+//
+// 回调在 mb 线程中执行，耗时的操作请使用 IPC
+func (js *JS) BindObject(name string, obj interface{}) {
+	rv := reflect.ValueOf(obj)
+	valid := rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct ||
+		rv.Kind() == reflect.Map && !rv.IsNil() && rv.Type().Key().Kind() == reflect.String
+	if !valid {
+		panic(fmt.Sprintf("BindObject %s: 需要结构体指针或键为字符串的 map，实际为 %T", name, obj))
+	}
+
+	js.bindOnce.Do(js.initBindCallbacks)
+
+	b := js.newBinding(name, rv, reflect.Value{})
+	if rv.Kind() == reflect.Ptr {
+		b.fields = cast.JSONFields(rv.Type())
+	}
+	if locker, ok := obj.(sync.Locker); ok {
+		b.locker = locker
+	}
+
+	_, _, _ = js.call("wkeJsBindGetter", StringToPtr(name), js.callbacks.getter, uintptr(unsafe.Pointer(&b.data)))
+}
+
+// 将 GO 函数绑定为 window[name]，JS 中同步调用，参数、返回值的转换及异常同 BindObject 的方法
+//
+//	mb.BindFunction("add", func(a, b int) (int, error) { return a + b, nil })
+//	// JS: const sum = add(1, 2)
+func (js *JS) BindFunction(name string, fn interface{}) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		panic(fmt.Sprintf("BindFunction %s: 需要函数，实际为 %T", name, fn))
+	}
+
+	js.bindOnce.Do(js.initBindCallbacks)
+
+	b := js.newBinding(name, rv, rv)
+
+	_, _, _ = js.call("wkeJsBindFunction", StringToPtr(name), js.callbacks.function, uintptr(unsafe.Pointer(&b.data)), uintptr(rv.Type().NumIn()))
+}
+
+func (js *JS) newBinding(name string, value, method reflect.Value) *jsBinding {
+	b := &jsBinding{
+		value:   value,
+		method:  method,
+		methods: make(map[string]*jsBinding),
+	}
+	copy(b.data.Name[:len(b.data.Name)-1], name)
+	b.data.PropertyGet = js.callbacks.propertyGet
+	b.data.PropertySet = js.callbacks.propertySet
+	b.data.Finalize = js.callbacks.finalize
+	b.data.CallAsFunction = js.callbacks.callAsFunction
+
+	js.bindMu.Lock()
+	js.bindings[uintptr(unsafe.Pointer(&b.data))] = b
+	js.bindMu.Unlock()
+
+	return b
+}
+
+func (js *JS) bindingOf(data *JsData) *jsBinding {
+	js.bindMu.Lock()
+	defer js.bindMu.Unlock()
+	return js.bindings[uintptr(unsafe.Pointer(data))]
+}
+
+func (js *JS) initBindCallbacks() {
+	js.callbacks.getter = CallbackToPtr(func(es JsExecState, param uintptr) uintptr {
+		b := js.bindingOf(AssertType[JsData](param))
+		if b == nil {
+			return uintptr(js.Undefined())
+		}
+		return uintptr(js.Object(es, &b.data))
+	})
+
+	js.callbacks.function = CallbackToPtr(func(es JsExecState, param uintptr) uintptr {
+		b := js.bindingOf(AssertType[JsData](param))
+		if b == nil {
+			return uintptr(js.Undefined())
+		}
+
+		args := make([]JsValue, js.ArgCount(es))
+		for i := range args {
+			args[i] = js.Arg(es, uint32(i))
+		}
+		return uintptr(b.call(js, es, args))
+	})
+
+	js.callbacks.propertyGet = CallbackToPtr(func(es JsExecState, object JsValue, propertyName uintptr) uintptr {
+		b := js.bindingOf(js.GetData(es, object))
+		if b == nil {
+			return uintptr(js.Undefined())
+		}
+		v, err := b.get(js, es, PtrToString(propertyName))
+		if err != nil {
+			return uintptr(js.ThrowException(es, err.Error()))
+		}
+		return uintptr(v)
+	})
+
+	js.callbacks.propertySet = CallbackToPtr(func(es JsExecState, object JsValue, propertyName uintptr, value JsValue) uintptr {
+		b := js.bindingOf(js.GetData(es, object))
+		if b == nil {
+			return 0
+		}
+		if err := b.set(js, es, PtrToString(propertyName), value); err != nil {
+			js.ThrowException(es, err.Error())
+			return 0
+		}
+		return 1
+	})
+
+	// jsData 由 GO 持有，JS 对象回收时无需释放
+	js.callbacks.finalize = CallbackToPtr(func(data uintptr) uintptr {
+		return 0
+	})
+
+	js.callbacks.callAsFunction = CallbackToPtr(func(es JsExecState, object JsValue, args uintptr, argCount uintptr) uintptr {
+		b := js.bindingOf(js.GetData(es, object))
+		if b == nil || !b.method.IsValid() {
+			return uintptr(js.ThrowException(es, "不是可调用的 GO 方法"))
+		}
+
+		var jsArgs []JsValue
+		if int32(argCount) > 0 {
+			jsArgs = unsafe.Slice(AssertType[JsValue](args), int(int32(argCount)))
+		}
+
+		return uintptr(b.call(js, es, jsArgs))
+	})
+}
+
+// 调用绑定的函数或方法
+//
+// 不加锁：方法通常会自行对接收者加锁，此处再加锁会使其死锁
+func (b *jsBinding) call(js *JS, es JsExecState, args []JsValue) JsValue {
+	return js.callGo(es, b.method, args)
+}
+
+func (b *jsBinding) lock() {
+	if b.locker != nil {
+		b.locker.Lock()
+	}
+}
+
+func (b *jsBinding) unlock() {
+	if b.locker != nil {
+		b.locker.Unlock()
+	}
+}
+
+// 读取属性：字段或 map 的值，方法则返回可调用的 JS 函数，不存在时返回 undefined
+func (b *jsBinding) get(js *JS, es JsExecState, prop string) (JsValue, error) {
+	if m := b.methodBinding(js, prop); m != nil {
+		return js.Function(es, &m.data), nil
+	}
+
+	b.lock()
+	defer b.unlock()
+
+	var value reflect.Value
+	if b.value.Kind() == reflect.Map {
+		value = b.value.MapIndex(reflect.ValueOf(prop).Convert(b.value.Type().Key()))
+	} else if f, ok := cast.FieldByName(b.fields, prop); ok {
+		value, _ = cast.FieldByIndex(b.value.Elem(), f.Index)
+	}
+
+	if !value.IsValid() || !value.CanInterface() {
+		return js.Undefined(), nil
+	}
+	return js.ToJsValueE(es, value.Interface())
+}
+
+// 写入属性：按字段或 map 的值类型严格转换，不存在的字段返回错误
+func (b *jsBinding) set(js *JS, es JsExecState, prop string, value JsValue) error {
+	input, err := js.ToGoValueE(es, value)
+	if err != nil {
+		return err
+	}
+
+	b.lock()
+	defer b.unlock()
+
+	if b.value.Kind() == reflect.Map {
+		v, err := cast.ParamStrict(b.value.Type().Elem(), input)
+		if err != nil {
+			return fmt.Errorf("%s: %s", prop, err.Error())
+		}
+		b.value.SetMapIndex(reflect.ValueOf(prop).Convert(b.value.Type().Key()), v)
+		return nil
+	}
+
+	f, ok := cast.FieldByName(b.fields, prop)
+	if !ok {
+		return fmt.Errorf("%s: 未定义的字段", prop)
+	}
+	field, exist := cast.FieldByIndex(b.value.Elem(), f.Index)
+	if !exist || !field.CanSet() {
+		return fmt.Errorf("%s: 字段不可写", prop)
+	}
+	v, err := cast.ParamStrict(f.Type, input)
+	if err != nil {
+		return fmt.Errorf("%s: %s", prop, err.Error())
+	}
+	field.Set(v)
+	return nil
+}
+
+// 按名称（忽略大小写）查找对象的方法，找不到时返回 nil
+func (b *jsBinding) methodBinding(js *JS, prop string) *jsBinding {
+	if b.method.IsValid() {
+		return nil
+	}
+
+	t := b.value.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		name := t.Method(i).Name
+		if !strings.EqualFold(name, prop) {
+			continue
+		}
+
+		js.bindMu.Lock()
+		m, exist := b.methods[name]
+		js.bindMu.Unlock()
+		if !exist {
+			m = js.newBinding(name, b.value, b.value.Method(i))
+			m.locker = b.locker
+			js.bindMu.Lock()
+			b.methods[name] = m
+			js.bindMu.Unlock()
+		}
+		return m
+	}
+
+	return nil
+}
+
+// 以 JS 参数调用 GO 函数，参数不足时视为 undefined，多余的参数忽略
+//
+// 最后一个返回值为 error 且不为 nil 时抛出 JS 异常，否则返回第一个返回值
+func (js *JS) callGo(es JsExecState, fn reflect.Value, args []JsValue) (result JsValue) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = js.ThrowException(es, fmt.Sprintf("%v", r))
+		}
+	}()
+
+	ft := fn.Type()
+	numIn := ft.NumIn()
+	count := numIn
+	if ft.IsVariadic() && len(args) > numIn-1 {
+		count = len(args)
+	} else if ft.IsVariadic() {
+		count = numIn - 1
+	}
+
+	in := make([]reflect.Value, count)
+	for i := 0; i < count; i++ {
+		var t reflect.Type
+		if ft.IsVariadic() && i >= numIn-1 {
+			t = ft.In(numIn - 1).Elem()
+		} else {
+			t = ft.In(i)
+		}
+
+		var input interface{}
+		if i < len(args) {
+			v, err := js.ToGoValueE(es, args[i])
+			if err != nil {
+				return js.ThrowException(es, fmt.Sprintf("参数 %d: %s", i, err.Error()))
+			}
+			input = v
+		}
+
+		v, err := cast.ConvertParam(js.strictArgs, t, input)
+		if err != nil {
+			return js.ThrowException(es, fmt.Sprintf("参数 %d: %s", i, err.Error()))
+		}
+		in[i] = v
+	}
+
+	out := fn.Call(in)
+
+	if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return js.ThrowException(es, err.Error())
+		}
+		out = out[:n-1]
+	}
+
+	if len(out) == 0 {
+		return js.Undefined()
+	}
+
+	v, err := js.ToJsValueE(es, out[0].Interface())
+	if err != nil {
+		return js.ThrowException(es, err.Error())
+	}
+	return v
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
@@ -0,0 +1,176 @@
+package blink
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/epkgs/blink/internal/cast"
+)
+
+// 以 JS 参数调用绑定的 GO 函数，返回转换后的结果及抛出的异常
+func callBound(t *testing.T, js *JS, e *fakeJsEngine, fn interface{}, args ...interface{}) (interface{}, string) {
+	t.Helper()
+
+	jsArgs := make([]JsValue, len(args))
+	for i, arg := range args {
+		v, err := js.ToJsValueE(0, arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsArgs[i] = v
+	}
+
+	e.thrown = nil
+	result := js.callGo(0, reflect.ValueOf(fn), jsArgs)
+	if len(e.thrown) > 0 {
+		return nil, e.thrown[0]
+	}
+
+	v, err := js.ToGoValueE(0, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, ""
+}
+
+func TestJsCallGoArgs(t *testing.T) {
+	add := func(a int, b uint8) int {
+		return a + int(b)
+	}
+	greet := func(name string, times int) string {
+		return strings.Repeat(name, times)
+	}
+
+	tests := []struct {
+		name   string
+		fn     interface{}
+		args   []interface{}
+		strict interface{} // 严格模式的结果，string 类型且以 "!" 开头时表示应抛出包含其后内容的异常
+		loose  interface{} // 宽松模式（WithIPCLenientArgs）的结果，规则同上
+	}{
+		{"正常", add, []interface{}{1, 2}, float64(3), float64(3)},
+		{"小数", add, []interface{}{1.5, 2}, "!参数 0", float64(3)},
+		{"字符串数字", add, []interface{}{"4", 2}, "!参数 0", float64(6)},
+		{"非数字", add, []interface{}{"abc", 2}, "!参数 0", float64(2)},
+		{"溢出", add, []interface{}{1, 300}, "!参数 1", nil},
+		{"字符串", greet, []interface{}{"ab", 2}, "abab", "abab"},
+		{"数字转字符串", greet, []interface{}{12, 2}, "!参数 0", "1212"},
+		{"缺少参数", greet, []interface{}{"ab"}, "!参数 1", ""},
+	}
+
+	for _, strict := range []bool{true, false} {
+		js, e := newFakeJS(t)
+		js.strictArgs = strict
+
+		for _, tt := range tests {
+			want := tt.loose
+			if strict {
+				want = tt.strict
+			}
+			if want == nil {
+				continue
+			}
+
+			got, thrown := callBound(t, js, e, tt.fn, tt.args...)
+
+			if s, ok := want.(string); ok && strings.HasPrefix(s, "!") {
+				if !strings.Contains(thrown, s[1:]) {
+					t.Errorf("strict=%v %s: 应抛出 %q，实际结果 %#v，异常 %q", strict, tt.name, s[1:], got, thrown)
+				}
+				continue
+			}
+			if thrown != "" || !reflect.DeepEqual(got, want) {
+				t.Errorf("strict=%v %s: got %#v (异常 %q), want %#v", strict, tt.name, got, thrown, want)
+			}
+		}
+	}
+}
+
+func TestJsCallGoResults(t *testing.T) {
+	js, e := newFakeJS(t)
+	js.strictArgs = true
+
+	type point struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+
+	tests := []struct {
+		name   string
+		fn     interface{}
+		args   []interface{}
+		want   interface{}
+		thrown string
+	}{
+		{"无返回值", func() {}, nil, nil, ""},
+		{"返回 error", func() error { return errors.New("boom") }, nil, nil, "boom"},
+		{"返回 nil error", func() (int, error) { return 1, nil }, nil, float64(1), ""},
+		{"值与 error", func() (int, error) { return 1, errors.New("bad") }, nil, nil, "bad"},
+		{"结构体", func(p point) point { return point{p.Y, p.X} }, []interface{}{point{1, 2}}, map[string]interface{}{"x": float64(2), "y": float64(1)}, ""},
+		{"多余参数忽略", func(a int) int { return a }, []interface{}{1, 2, 3}, float64(1), ""},
+		{"缺少的指针参数为 nil", func(a int, p *point) string { return fmt.Sprint(a, p == nil) }, []interface{}{1}, "1 true", ""},
+		{"可变参数", func(sep string, parts ...string) string { return strings.Join(parts, sep) }, []interface{}{"-", "a", "b"}, "a-b", ""},
+		{"panic", func() { panic("oops") }, nil, nil, "oops"},
+	}
+
+	for _, tt := range tests {
+		got, thrown := callBound(t, js, e, tt.fn, tt.args...)
+		if thrown != tt.thrown || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v (异常 %q), want %#v (异常 %q)", tt.name, got, thrown, tt.want, tt.thrown)
+		}
+	}
+}
+
+type lockedCounter struct {
+	sync.Mutex
+	Count int `json:"count"`
+}
+
+func (c *lockedCounter) Incr() int {
+	c.Lock()
+	defer c.Unlock()
+	c.Count++
+	return c.Count
+}
+
+// 对象实现了 sync.Locker 时，读写属性加锁，调用自行加锁的方法不能死锁
+func TestJsBindSelfLockingMethod(t *testing.T) {
+	js, e := newFakeJS(t)
+
+	counter := &lockedCounter{}
+	b := js.newBinding("counter", reflect.ValueOf(counter), reflect.Value{})
+	b.fields = cast.JSONFields(reflect.TypeOf(counter))
+	b.locker = counter
+
+	m := b.methodBinding(js, "incr")
+	if m == nil {
+		t.Fatal("未找到方法 incr")
+	}
+
+	done := make(chan JsValue, 1)
+	go func() {
+		done <- m.call(js, 0, nil)
+	}()
+
+	select {
+	case result := <-done:
+		if got, err := js.ToGoValueE(0, result); err != nil || got != float64(1) {
+			t.Fatalf("incr() = %#v, %v", got, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("调用自行加锁的方法时死锁")
+	}
+
+	v, err := b.get(js, 0, "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := js.ToGoValueE(0, v); got != float64(1) || len(e.thrown) > 0 {
+		t.Fatalf("count = %#v, 异常 %v", got, e.thrown)
+	}
+}
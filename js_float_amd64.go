@@ -0,0 +1,17 @@
+//go:build amd64
+
+package blink
+
+import "math"
+
+// amd64 下 syscall 会把前 4 个参数同时放入 XMM0-3，double 以其位模式传入即可
+func (js *JS) Double(value float64) JsValue {
+	r, _, _ := js.call("jsDouble", uintptr(math.Float64bits(value)))
+	return JsValue(r)
+}
+
+// amd64 下 double 返回值位于 XMM0，syscall 将其放在 r2 中返回
+func (js *JS) ToDouble(es JsExecState, value JsValue) float64 {
+	_, r2, _ := js.call("jsToDouble", uintptr(es), uintptr(value))
+	return math.Float64frombits(uint64(r2))
+}
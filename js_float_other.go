@@ -0,0 +1,34 @@
+//go:build !amd64
+
+package blink
+
+import (
+	"math"
+	"strconv"
+)
+
+// 32 位下 double 经栈传递、经 x87 寄存器返回，syscall 无法处理，改用字符串形式传递
+func (js *JS) Double(value float64) JsValue {
+	var s string
+	switch {
+	case math.IsNaN(value):
+		s = "NaN"
+	case math.IsInf(value, 1):
+		s = "Infinity"
+	case math.IsInf(value, -1):
+		s = "-Infinity"
+	default:
+		s = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	r, _, _ := js.call("jsDoubleString", StringToPtr(s))
+	return JsValue(r)
+}
+
+func (js *JS) ToDouble(es JsExecState, value JsValue) float64 {
+	p, _, _ := js.call("jsToDoubleString", uintptr(es), uintptr(value))
+	f, err := strconv.ParseFloat(PtrToString(p), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
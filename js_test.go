@@ -0,0 +1,569 @@
+package blink
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// 模拟的 JS 值
+type fakeJsValue struct {
+	typ     JsType
+	num     float64
+	str     string
+	boolean bool
+	bigint  bool // 由 BigInt(...) 创建，str 为其十进制数字
+	items   []JsValue
+	keys    []string
+	props   map[string]JsValue
+}
+
+// 模拟的 JS 引擎，实现 JS 用到的 mb 导出函数，JsValue 为 values 的下标加 1
+type fakeJsEngine struct {
+	t      *testing.T
+	values []*fakeJsValue
+	keep   []interface{} // 返回给 GO 的字符串、JsKeys，须在测试期间保持存活
+	thrown []string      // 抛出的 JS 异常
+}
+
+func newFakeJS(t *testing.T) (*JS, *fakeJsEngine) {
+	e := &fakeJsEngine{t: t}
+	js := &JS{call: e.call, bindings: make(map[uintptr]*jsBinding)}
+	return js, e
+}
+
+func (e *fakeJsEngine) new(v *fakeJsValue) uintptr {
+	e.values = append(e.values, v)
+	return uintptr(len(e.values))
+}
+
+func (e *fakeJsEngine) value(v JsValue) *fakeJsValue {
+	return e.values[v-1]
+}
+
+func (e *fakeJsEngine) cString(s string) uintptr {
+	b := append([]byte(s), 0)
+	e.keep = append(e.keep, b)
+	return uintptr(unsafe.Pointer(&b[0]))
+}
+
+// 按 JS 的规则将数字转为字符串
+func jsNumberString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var fakeBigIntRe = regexp.MustCompile(`BigInt\("(-?\d+)"\)`)
+
+func (e *fakeJsEngine) call(funcName string, args ...uintptr) (uintptr, uintptr, error) {
+	switch funcName {
+	case "jsUndefined":
+		return e.new(&fakeJsValue{typ: JsType_UNDEFINED}), 0, nil
+	case "jsInt":
+		return e.new(&fakeJsValue{typ: JsType_NUMBER, num: float64(int32(args[0]))}), 0, nil
+	case "jsDouble":
+		return e.new(&fakeJsValue{typ: JsType_NUMBER, num: math.Float64frombits(uint64(args[0]))}), 0, nil
+	case "jsDoubleString":
+		f, err := strconv.ParseFloat(PtrToString(args[0]), 64)
+		if err != nil {
+			e.t.Fatalf("jsDoubleString: %v", err)
+		}
+		return e.new(&fakeJsValue{typ: JsType_NUMBER, num: f}), 0, nil
+	case "jsBoolean":
+		return e.new(&fakeJsValue{typ: JsType_BOOLEAN, boolean: args[0] != 0}), 0, nil
+	case "jsString":
+		return e.new(&fakeJsValue{typ: JsType_STRING, str: PtrToString(args[1])}), 0, nil
+	case "jsEmptyArray":
+		return e.new(&fakeJsValue{typ: JsType_ARRAY}), 0, nil
+	case "jsEmptyObject":
+		return e.new(&fakeJsValue{typ: JsType_OBJECT, props: map[string]JsValue{}}), 0, nil
+	case "jsEvalW":
+		script := PtrWCharToString(args[1])
+		m := fakeBigIntRe.FindStringSubmatch(script)
+		if m == nil {
+			e.t.Fatalf("jsEvalW: 不支持的脚本 %s", script)
+		}
+		return e.new(&fakeJsValue{typ: JsType_OBJECT, bigint: true, str: m[1]}), 0, nil
+
+	case "jsThrowException":
+		e.thrown = append(e.thrown, PtrToString(args[1]))
+		return e.new(&fakeJsValue{typ: JsType_UNDEFINED}), 0, nil
+
+	case "jsSetLength":
+		v := e.value(JsValue(args[1]))
+		items := make([]JsValue, args[2])
+		copy(items, v.items)
+		v.items = items
+		return 0, 0, nil
+	case "jsSetAt":
+		e.value(JsValue(args[1])).items[args[2]] = JsValue(args[3])
+		return 0, 0, nil
+	case "jsSet":
+		v, k := e.value(JsValue(args[1])), PtrToString(args[2])
+		if _, exist := v.props[k]; !exist {
+			v.keys = append(v.keys, k)
+		}
+		v.props[k] = JsValue(args[3])
+		return 0, 0, nil
+
+	case "jsTypeOf":
+		return uintptr(e.value(JsValue(args[0])).typ), 0, nil
+	case "jsToDouble":
+		return 0, uintptr(math.Float64bits(e.value(JsValue(args[1])).num)), nil
+	case "jsToDoubleString":
+		return e.cString(jsNumberString(e.value(JsValue(args[1])).num)), 0, nil
+	case "jsToBoolean":
+		return BoolToPtr(e.value(JsValue(args[1])).boolean), 0, nil
+	case "jsToTempString", "jsToString":
+		return e.cString(e.value(JsValue(args[1])).str), 0, nil
+	case "jsGetLength":
+		return uintptr(len(e.value(JsValue(args[1])).items)), 0, nil
+	case "jsGetAt":
+		return uintptr(e.value(JsValue(args[1])).items[args[2]]), 0, nil
+	case "jsGet":
+		if v, exist := e.value(JsValue(args[1])).props[PtrToString(args[2])]; exist {
+			return uintptr(v), 0, nil
+		}
+		return e.new(&fakeJsValue{typ: JsType_UNDEFINED}), 0, nil
+	case "jsGetKeys":
+		v := e.value(JsValue(args[1]))
+		ptrs := make([]uintptr, len(v.keys)+1)
+		for i, k := range v.keys {
+			ptrs[i] = e.cString(k)
+		}
+		keys := &JsKeys{Length: uint32(len(v.keys)), First: uintptr(unsafe.Pointer(&ptrs[0]))}
+		e.keep = append(e.keep, ptrs, keys)
+		return uintptr(unsafe.Pointer(keys)), 0, nil
+	}
+
+	e.t.Fatalf("模拟的 JS 引擎未实现 %s", funcName)
+	return 0, 0, nil
+}
+
+func TestJsLargeIntPolicy(t *testing.T) {
+	js, e := newFakeJS(t)
+
+	type want struct {
+		typ    JsType
+		num    float64
+		str    string
+		bigint bool
+	}
+	number := func(f float64) want { return want{typ: JsType_NUMBER, num: f} }
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		policy JsLargeIntPolicy
+		want   want
+	}{
+		{"int32", int64(math.MaxInt32), JS_LARGE_INT_STRING, number(math.MaxInt32)},
+		{"负 int32", int64(math.MinInt32), JS_LARGE_INT_STRING, number(math.MinInt32)},
+		{"最大安全整数", int64(JS_MAX_SAFE_INTEGER), JS_LARGE_INT_STRING, number(JS_MAX_SAFE_INTEGER)},
+		{"最小安全整数", int64(-JS_MAX_SAFE_INTEGER), JS_LARGE_INT_BIGINT, number(-JS_MAX_SAFE_INTEGER)},
+		{"uint64 安全整数", uint64(JS_MAX_SAFE_INTEGER), JS_LARGE_INT_STRING, number(JS_MAX_SAFE_INTEGER)},
+
+		{"int64 字符串", int64(math.MaxInt64), JS_LARGE_INT_STRING, want{typ: JsType_STRING, str: "9223372036854775807"}},
+		{"负 int64 字符串", int64(math.MinInt64), JS_LARGE_INT_STRING, want{typ: JsType_STRING, str: "-9223372036854775808"}},
+		{"2^53 字符串", int64(JS_MAX_SAFE_INTEGER + 1), JS_LARGE_INT_STRING, want{typ: JsType_STRING, str: "9007199254740992"}},
+		{"uint64 字符串", uint64(math.MaxUint64), JS_LARGE_INT_STRING, want{typ: JsType_STRING, str: "18446744073709551615"}},
+
+		{"int64 BigInt", int64(math.MaxInt64), JS_LARGE_INT_BIGINT, want{typ: JsType_OBJECT, str: "9223372036854775807", bigint: true}},
+		{"负 int64 BigInt", int64(-JS_MAX_SAFE_INTEGER - 2), JS_LARGE_INT_BIGINT, want{typ: JsType_OBJECT, str: "-9007199254740993", bigint: true}},
+		{"uint64 BigInt", uint64(math.MaxUint64), JS_LARGE_INT_BIGINT, want{typ: JsType_OBJECT, str: "18446744073709551615", bigint: true}},
+
+		{"int64 数字", int64(math.MaxInt64), JS_LARGE_INT_NUMBER, number(math.MaxInt64)},
+		{"uint64 数字", uint64(math.MaxUint64), JS_LARGE_INT_NUMBER, number(math.MaxUint64)},
+		{"2^53+1 数字", int64(JS_MAX_SAFE_INTEGER + 2), JS_LARGE_INT_NUMBER, number(JS_MAX_SAFE_INTEGER + 1)},
+	}
+
+	for _, tt := range tests {
+		v, err := js.ToJsValueE(0, tt.value, WithJsLargeIntPolicy(tt.policy))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := e.value(v)
+		if got.typ != tt.want.typ || got.num != tt.want.num || got.str != tt.want.str || got.bigint != tt.want.bigint {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestJsTimePolicy(t *testing.T) {
+	js, e := newFakeJS(t)
+
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.FixedZone("CST", 8*3600))
+
+	type event struct {
+		At   time.Time  `json:"at"`
+		Next *time.Time `json:"next,omitempty"`
+	}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		policy JsTimePolicy
+		want   interface{}
+	}{
+		{"默认为毫秒", tm, JS_TIME_MILLIS, float64(tm.UnixMilli())},
+		{"ISO 转为 UTC", tm, JS_TIME_ISO, "2024-01-01T19:04:05.006Z"},
+		{"毫秒截断", tm.Add(999 * time.Microsecond), JS_TIME_MILLIS, float64(tm.UnixMilli())},
+		{"纪元前", time.UnixMilli(-1500).UTC(), JS_TIME_MILLIS, float64(-1500)},
+		{"零值 ISO", time.Time{}, JS_TIME_ISO, "0001-01-01T00:00:00.000Z"},
+		{"指针", &tm, JS_TIME_ISO, "2024-01-01T19:04:05.006Z"},
+		{"结构体字段", event{At: tm, Next: &tm}, JS_TIME_ISO, map[string]interface{}{
+			"at":   "2024-01-01T19:04:05.006Z",
+			"next": "2024-01-01T19:04:05.006Z",
+		}},
+		{"结构体字段毫秒", event{At: tm}, JS_TIME_MILLIS, map[string]interface{}{
+			"at": float64(tm.UnixMilli()),
+		}},
+	}
+
+	for _, tt := range tests {
+		v, err := js.ToJsValueE(0, tt.value, WithJsTimePolicy(tt.policy))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		got, err := js.ToGoValueE(0, v)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+		if tt.policy == JS_TIME_ISO && e.value(v).typ == JsType_NUMBER {
+			t.Errorf("%s: ISO 应转为字符串", tt.name)
+		}
+	}
+}
+
+// Double、ToDouble 经模拟的 JS 引擎往返后须保持相同的值（amd64 下按位模式传递，其他平台按字符串传递）
+func TestJsDoubleRoundTrip(t *testing.T) {
+	js, _ := newFakeJS(t)
+
+	tests := []float64{
+		0,
+		math.Copysign(0, -1),
+		1.5,
+		-2.25,
+		0.1,
+		1.0 / 3,
+		1e21,
+		1e-7,
+		math.Pi,
+		math.MaxFloat64,
+		-math.MaxFloat64,
+		math.SmallestNonzeroFloat64,
+		float64(JS_MAX_SAFE_INTEGER),
+		float64(JS_MAX_SAFE_INTEGER + 1),
+		math.Inf(1),
+		math.Inf(-1),
+		math.NaN(),
+	}
+
+	for _, f := range tests {
+		got := js.ToDouble(0, js.Double(f))
+
+		if math.IsNaN(f) {
+			if !math.IsNaN(got) {
+				t.Errorf("NaN: got %v", got)
+			}
+			continue
+		}
+		if math.Float64bits(got) != math.Float64bits(f) {
+			t.Errorf("%v: got %v (%#x), want %#x", f, got, math.Float64bits(got), math.Float64bits(f))
+		}
+	}
+}
+
+func TestJsValueRoundTrip(t *testing.T) {
+	js, _ := newFakeJS(t)
+
+	type inner struct {
+		Rate float64 `json:"rate"`
+	}
+	type payload struct {
+		Name  string            `json:"name"`
+		Count int               `json:"count"`
+		Ok    bool              `json:"ok"`
+		Tags  []string          `json:"tags"`
+		Inner inner             `json:"inner"`
+		Meta  map[string]uint32 `json:"meta"`
+		Skip  string            `json:"-"`
+		Empty string            `json:"empty,omitempty"`
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"nil", nil, nil},
+		{"int", -42, float64(-42)},
+		{"float32", float32(0.5), 0.5},
+		{"string", "你好", "你好"},
+		{"bool", true, true},
+		{"slice", []int{1, 2, 3}, []interface{}{float64(1), float64(2), float64(3)}},
+		{"struct", payload{
+			Name:  "a",
+			Count: 3,
+			Ok:    true,
+			Tags:  []string{"x"},
+			Inner: inner{Rate: 0.25},
+			Meta:  map[string]uint32{"n": math.MaxUint32},
+			Skip:  "skip",
+		}, map[string]interface{}{
+			"name":  "a",
+			"count": float64(3),
+			"ok":    true,
+			"tags":  []interface{}{"x"},
+			"inner": map[string]interface{}{"rate": 0.25},
+			"meta":  map[string]interface{}{"n": float64(math.MaxUint32)},
+		}},
+	}
+
+	for _, tt := range tests {
+		v, err := js.ToJsValueE(0, tt.value)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := js.ToGoValueE(0, v)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 值接收者实现了 json.Marshaler
+type jsonPoint struct{ X, Y int }
+
+func (p jsonPoint) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.Itoa(p.X) + "," + strconv.Itoa(p.Y))), nil
+}
+
+func TestJsNilMarshaler(t *testing.T) {
+	js, _ := newFakeJS(t)
+
+	type box struct {
+		Any interface{} `json:"any"`
+		Ptr *jsonPoint  `json:"ptr"`
+	}
+
+	var nilPoint *jsonPoint
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"值", jsonPoint{1, 2}, "1,2"},
+		{"指针", &jsonPoint{1, 2}, "1,2"},
+		{"nil 指针", nilPoint, nil},
+		{"接口中的 nil 指针", []interface{}{nilPoint, jsonPoint{3, 4}}, []interface{}{nil, "3,4"}},
+		{"字段", box{Any: nilPoint}, map[string]interface{}{"any": nil, "ptr": nil}},
+	}
+
+	for _, tt := range tests {
+		v, err := js.ToJsValueE(0, tt.value)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := js.ToGoValueE(0, v)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 在模拟的 JS 引擎中创建对象，keys 与 values 一一对应
+func (e *fakeJsEngine) object(keys []string, values ...JsValue) JsValue {
+	v := &fakeJsValue{typ: JsType_OBJECT, keys: keys, props: map[string]JsValue{}}
+	for i, k := range keys {
+		v.props[k] = values[i]
+	}
+	return JsValue(e.new(v))
+}
+
+func (e *fakeJsEngine) array(items ...JsValue) JsValue {
+	return JsValue(e.new(&fakeJsValue{typ: JsType_ARRAY, items: items}))
+}
+
+func (e *fakeJsEngine) function() JsValue {
+	return JsValue(e.new(&fakeJsValue{typ: JsType_FUNCTION}))
+}
+
+// 转换出错时，错误中的路径指向出错的值
+func TestJsConvertErrorPath(t *testing.T) {
+	js, e := newFakeJS(t)
+
+	type item struct {
+		Name    string      `json:"name"`
+		Handler interface{} `json:"handler"`
+	}
+	items := make([]item, 4)
+	items[3].Handler = func() {} // 其他元素的 Handler 为 nil，转为 undefined
+
+	tests := []struct {
+		name  string
+		value interface{}
+		opts  []JsConvertOption
+		path  string
+	}{
+		{"函数", map[string]interface{}{"items": items}, []JsConvertOption{WithJsFuncPolicy(JS_FUNC_ERROR)}, "$.items[3].handler"},
+		{"chan", map[string]interface{}{"list": []interface{}{1, make(chan int)}}, nil, "$.list[1]"},
+		{"map 键", []interface{}{map[int]string{1: "a"}}, nil, "$[0]"},
+	}
+
+	for _, tt := range tests {
+		_, err := js.ToJsValueE(0, tt.value, tt.opts...)
+		var ce *JsConvertError
+		if !errors.As(err, &ce) || ce.Path != tt.path {
+			t.Errorf("%s: err = %v, want path %s", tt.name, err, tt.path)
+		}
+	}
+
+	// JS -> GO
+	u := JsValue(e.new(&fakeJsValue{typ: JsType_UNDEFINED}))
+	obj := e.object([]string{"items"}, e.array(u, u, u, e.object([]string{"name", "handler"}, u, e.function())))
+	_, err := js.ToGoValueE(0, obj, WithJsFuncPolicy(JS_FUNC_ERROR))
+	var ce *JsConvertError
+	if !errors.As(err, &ce) || ce.Path != "$.items[3].handler" {
+		t.Errorf("ToGoValueE: err = %v, want path $.items[3].handler", err)
+	}
+}
+
+// 循环引用及超过最大深度时返回错误，而不是无限递归
+func TestJsConvertDepth(t *testing.T) {
+	js, e := newFakeJS(t)
+
+	type node struct {
+		Name string `json:"name"`
+		Next *node  `json:"next"`
+	}
+	cycle := &node{Name: "a"}
+	cycle.Next = &node{Name: "b", Next: cycle}
+
+	self := map[string]interface{}{}
+	self["self"] = self
+
+	deep := []interface{}{[]interface{}{[]interface{}{[]interface{}{1}}}}
+	shared := &node{Name: "shared"}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		opts   []JsConvertOption
+		path   string
+		reason string
+	}{
+		{"结构体循环引用", cycle, nil, "$.next.next", "循环引用"},
+		{"map 循环引用", self, nil, "$.self", "循环引用"},
+		{"超过最大深度", deep, []JsConvertOption{WithJsMaxDepth(2)}, "$[0][0][0]", "最大深度"},
+		{"未超过最大深度", deep, []JsConvertOption{WithJsMaxDepth(4)}, "", ""},
+		{"重复引用不是循环", []*node{shared, shared}, nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		_, err := js.ToJsValueE(0, tt.value, tt.opts...)
+		if tt.path == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var ce *JsConvertError
+		if !errors.As(err, &ce) || ce.Path != tt.path || !strings.Contains(ce.Reason, tt.reason) {
+			t.Errorf("%s: err = %v, want path %s (%s)", tt.name, err, tt.path, tt.reason)
+		}
+	}
+
+	// JS 对象的引用在 GO 中无法检测，由最大深度兜底
+	cyclic := &fakeJsValue{typ: JsType_OBJECT, keys: []string{"self"}, props: map[string]JsValue{}}
+	v := JsValue(e.new(cyclic))
+	cyclic.props["self"] = v
+	_, err := js.ToGoValueE(0, v, WithJsMaxDepth(3))
+	var ce *JsConvertError
+	if !errors.As(err, &ce) || ce.Path != "$.self.self.self.self" {
+		t.Errorf("ToGoValueE: err = %v", err)
+	}
+}
+
+func TestJsFuncPolicy(t *testing.T) {
+	js, e := newFakeJS(t)
+
+	type withFunc struct {
+		Name string `json:"name"`
+		Fn   func() `json:"fn"`
+	}
+	value := []interface{}{withFunc{Name: "a", Fn: func() {}}, func() {}}
+
+	// GO -> JS：JS_FUNC_HANDLE 仅用于 ToGoValueE，与 JS_FUNC_OMIT 相同
+	for _, policy := range []JsFuncPolicy{JS_FUNC_OMIT, JS_FUNC_HANDLE, JS_FUNC_ERROR} {
+		v, err := js.ToJsValueE(0, value, WithJsFuncPolicy(policy))
+		if policy == JS_FUNC_ERROR {
+			var ce *JsConvertError
+			if !errors.As(err, &ce) || ce.Path != "$[0].fn" {
+				t.Errorf("ToJsValueE %d: err = %v", policy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ToJsValueE %d: %v", policy, err)
+		}
+		got, _ := js.ToGoValueE(0, v)
+		want := []interface{}{map[string]interface{}{"name": "a"}, nil}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ToJsValueE %d: got %#v, want %#v", policy, got, want)
+		}
+	}
+
+	// JS -> GO
+	fn := e.function()
+	obj := e.object([]string{"name", "fn"}, js.String(0, "a"), fn)
+	arr := e.array(obj, fn)
+
+	got, err := js.ToGoValueE(0, arr, WithJsFuncPolicy(JS_FUNC_OMIT))
+	if want := []interface{}{map[string]interface{}{"name": "a"}, nil}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("JS_FUNC_OMIT: got %#v, %v", got, err)
+	}
+
+	_, err = js.ToGoValueE(0, arr, WithJsFuncPolicy(JS_FUNC_ERROR))
+	var ce *JsConvertError
+	if !errors.As(err, &ce) || ce.Path != "$[0].fn" {
+		t.Errorf("JS_FUNC_ERROR: err = %v", err)
+	}
+
+	got, err = js.ToGoValueE(0, arr, WithJsFuncPolicy(JS_FUNC_HANDLE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := got.([]interface{})
+	member, ok := items[0].(map[string]interface{})["fn"].(*JsFunc)
+	if !ok || member.Path != "$[0].fn" || member.fn != fn || member.this != obj {
+		t.Errorf("JS_FUNC_HANDLE: 对象属性 = %#v", items[0])
+	}
+	elem, ok := items[1].(*JsFunc)
+	if !ok || elem.Path != "$[1]" || elem.this != arr {
+		t.Errorf("JS_FUNC_HANDLE: 数组元素 = %#v", items[1])
+	}
+}
@@ -0,0 +1,102 @@
+package ipc
+
+import (
+	"sync"
+	"time"
+)
+
+// 合并发送：将 window 时间内的多条消息合并后交给 sink 一次发送，用于减少逐条发送的开销（如每条消息一次 RunJS）
+//
+// 等待的消息总长度达到 maxSize 时立即发送；sink 按消息的加入顺序调用，同一时刻只有一个 sink 在执行
+type Batcher struct {
+	window  time.Duration
+	maxSize int
+	sink    func(batch []string)
+
+	mu        sync.Mutex
+	queue     []string    // 等待合并发送的消息
+	queueSize int         // 等待合并发送的消息总长度
+	timer     *time.Timer // 合并发送的定时器，没有等待的消息时为 nil
+	closed    bool
+
+	flushMu sync.Mutex // 保证合并后的消息按顺序发送
+}
+
+// 创建合并发送器，maxSize <= 0 时为 DefaultMaxMessageSize
+func NewBatcher(window time.Duration, maxSize int, sink func(batch []string)) *Batcher {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+
+	return &Batcher{
+		window:  window,
+		maxSize: maxSize,
+		sink:    sink,
+	}
+}
+
+// 加入一条等待发送的消息，Close 后返回 ErrClosed
+func (b *Batcher) Add(data string) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	b.queue = append(b.queue, data)
+	b.queueSize += len(data)
+
+	// 积压过多时立即发送，避免单次发送过大
+	if b.queueSize >= b.maxSize {
+		b.mu.Unlock()
+		b.Flush()
+		return nil
+	}
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.Flush)
+	}
+	b.mu.Unlock()
+
+	return nil
+}
+
+// 立即发送等待的消息
+func (b *Batcher) Flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	queue := b.queue
+	b.reset()
+	b.mu.Unlock()
+
+	if len(queue) == 0 {
+		return
+	}
+
+	b.sink(queue)
+}
+
+// 丢弃等待发送的消息，如对端的页面已刷新，之前的消息已无意义
+func (b *Batcher) Discard() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+}
+
+// 丢弃等待发送的消息，之后不再接受新的消息
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	b.closed = true
+}
+
+func (b *Batcher) reset() {
+	b.queue, b.queueSize = nil, 0
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
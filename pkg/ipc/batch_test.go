@@ -0,0 +1,124 @@
+package ipc
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录每次合并发送的消息
+type batchSink struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (s *batchSink) send(batch []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+}
+
+func (s *batchSink) get() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.batches...)
+}
+
+func TestBatcherWindow(t *testing.T) {
+	sink := &batchSink{}
+	b := NewBatcher(20*time.Millisecond, 0, sink.send)
+
+	b.Add("a")
+	b.Add("b")
+	if got := sink.get(); len(got) != 0 {
+		t.Fatalf("窗口结束前不应发送: %v", got)
+	}
+
+	waitFor(t, "合并发送", func() bool { return len(sink.get()) == 1 })
+	if got, want := sink.get(), [][]string{{"a", "b"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches = %v, want %v", got, want)
+	}
+
+	// 窗口结束后的消息进入下一批
+	b.Add("c")
+	waitFor(t, "第二批", func() bool { return len(sink.get()) == 2 })
+	if got := sink.get()[1]; !reflect.DeepEqual(got, []string{"c"}) {
+		t.Fatalf("batch = %v", got)
+	}
+}
+
+func TestBatcherFlush(t *testing.T) {
+	sink := &batchSink{}
+	b := NewBatcher(time.Hour, 4, sink.send)
+
+	// 达到 maxSize 时立即发送
+	b.Add("ab")
+	b.Add("cd")
+	if got, want := sink.get(), [][]string{{"ab", "cd"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches = %v, want %v", got, want)
+	}
+
+	b.Add("e")
+	b.Flush()
+	b.Flush() // 没有等待的消息时不发送
+	if got, want := sink.get(), [][]string{{"ab", "cd"}, {"e"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches = %v, want %v", got, want)
+	}
+}
+
+// 脚本上下文释放时丢弃等待的消息，之后的消息照常发送；关闭后不再接受消息
+func TestBatcherDiscardAndClose(t *testing.T) {
+	sink := &batchSink{}
+	b := NewBatcher(20*time.Millisecond, 0, sink.send)
+
+	b.Add("stale")
+	b.Discard()
+
+	if err := b.Add("fresh"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "发送新消息", func() bool { return len(sink.get()) == 1 })
+
+	b.Add("pending")
+	b.Close()
+	if err := b.Add("late"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+	b.Flush()
+
+	time.Sleep(50 * time.Millisecond)
+	if got, want := sink.get(), [][]string{{"fresh"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches = %v, want %v", got, want)
+	}
+}
+
+// 定时发送与达到 maxSize 的发送交错时，消息仍按加入顺序到达
+func TestBatcherOrder(t *testing.T) {
+	sink := &batchSink{}
+	b := NewBatcher(time.Millisecond, 16, sink.send)
+
+	const n = 500
+	for i := 0; i < n; i++ {
+		b.Add(strconv.Itoa(i))
+		if i%50 == 0 {
+			time.Sleep(2 * time.Millisecond)
+		}
+	}
+	b.Flush()
+
+	var got []string
+	for _, batch := range sink.get() {
+		got = append(got, batch...)
+	}
+	if len(got) != n {
+		t.Fatalf("收到 %d 条消息，期望 %d 条", len(got), n)
+	}
+	for i, s := range got {
+		if s != strconv.Itoa(i) {
+			t.Fatalf("第 %d 条消息为 %s", i, s)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package ipc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/epkgs/blink/internal/cast"
+)
+
+// 二进制数据在 JSON 中的封装：{"__ipc_bin__": "<base64>"}，JS 端还原为 Uint8Array
+const BINARY_KEY = "__ipc_bin__"
+
+// 二进制数据
+//
+// 发送时，消息中任意位置的 []byte（包括结构体字段）都会自动封装；
+// 结构体字段声明为 Binary 时，HandleTyped 等通过 encoding/json 解码的场景同样能识别封装
+type Binary []byte
+
+func (b Binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{BINARY_KEY: base64.StdEncoding.EncodeToString(b)})
+}
+
+// 兼容封装与 base64 字符串
+func (b *Binary) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var envelope map[string]string
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return err
+		}
+		s = envelope[BINARY_KEY]
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+// 封装二进制数据时的最大嵌套深度
+const maxBinaryDepth = 1000
+
+// 将 v 中的 []byte 封装为 Binary，不含 []byte 的值原样返回
+//
+// 存在循环引用或嵌套过深时返回错误，与 json.Marshal 一致
+func encodeBinary(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if !mayHaveBytes(rv.Type(), map[reflect.Type]bool{}) {
+		return v, nil
+	}
+
+	e := &binaryEncoder{visiting: map[uintptr]bool{}}
+	return e.encode(rv, 0)
+}
+
+func encodeBinaryValues(values []interface{}) ([]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		encoded, err := encodeBinary(v)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = encoded
+	}
+	return result, nil
+}
+
+type binaryEncoder struct {
+	visiting map[uintptr]bool // 当前路径上的指针、map、slice，用于检测循环引用
+}
+
+func (e *binaryEncoder) encode(rv reflect.Value, depth int) (interface{}, error) {
+	t := rv.Type()
+
+	// 自定义了序列化的类型（包括 Binary、json.RawMessage），不做处理
+	if cast.IsMarshaler(t) {
+		return rv.Interface(), nil
+	}
+
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return Binary(rv.Bytes()), nil
+	}
+
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("ipc: 封装二进制数据时超过最大嵌套深度 %d", maxBinaryDepth)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			break
+		}
+		ptr := rv.Pointer()
+		if t.Kind() != reflect.Slice || rv.Len() > 0 {
+			if e.visiting[ptr] {
+				return nil, fmt.Errorf("ipc: 封装二进制数据时检测到循环引用: %s", t)
+			}
+			e.visiting[ptr] = true
+			defer delete(e.visiting, ptr)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return e.encode(rv.Elem(), depth+1)
+
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		result := make([]interface{}, rv.Len())
+		for i := range result {
+			item, err := e.encode(rv.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = item
+		}
+		return result, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String || rv.IsNil() {
+			return rv.Interface(), nil
+		}
+		result := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			item, err := e.encode(iter.Value(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			result[iter.Key().String()] = item
+		}
+		return result, nil
+
+	case reflect.Struct:
+		if cast.IsMarshaler(reflect.PtrTo(t)) {
+			return rv.Interface(), nil
+		}
+		result := map[string]interface{}{}
+		for _, f := range cast.JSONFields(t) {
+			fv, ok := cast.FieldByIndex(rv, f.Index)
+			if !ok || (f.OmitEmpty && cast.IsEmptyValue(fv)) {
+				continue
+			}
+			if f.Quoted {
+				data, _ := json.Marshal(fv.Interface())
+				result[f.Name] = string(data)
+				continue
+			}
+			item, err := e.encode(fv, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			result[f.Name] = item
+		}
+		return result, nil
+
+	default:
+		return rv.Interface(), nil
+	}
+}
+
+// 类型中是否可能含有 []byte
+func mayHaveBytes(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return mayHaveBytes(t.Elem(), seen)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && mayHaveBytes(t.Elem(), seen)
+	case reflect.Struct:
+		for _, f := range cast.JSONFields(t) {
+			if mayHaveBytes(f.Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// 将对端发来的值中的二进制封装还原为 []byte
+func decodeBinary(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 1 {
+			if s, ok := val[BINARY_KEY].(string); ok {
+				if data, err := base64.StdEncoding.DecodeString(s); err == nil {
+					return data
+				}
+			}
+		}
+		for k, item := range val {
+			val[k] = decodeBinary(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = decodeBinary(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
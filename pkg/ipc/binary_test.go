@@ -0,0 +1,56 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type binaryNode struct {
+	Name string
+	Data []byte
+	Any  interface{}
+	Next *binaryNode
+}
+
+func TestEncodeBinaryCycle(t *testing.T) {
+	n := &binaryNode{Name: "a", Data: []byte{1}}
+	n.Next = n
+
+	if _, err := encodeBinary(n); err == nil {
+		t.Fatal("循环引用应返回错误")
+	}
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	if _, err := encodeBinary(m); err == nil {
+		t.Fatal("循环引用的 map 应返回错误")
+	}
+
+	// 同一个值被引用多次但不构成循环时，正常封装
+	shared := []byte{1, 2}
+	v, err := encodeBinary([]interface{}{shared, shared, &binaryNode{Any: shared}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(v)
+	want := `[{"__ipc_bin__":"AQI="},{"__ipc_bin__":"AQI="},{"Any":{"__ipc_bin__":"AQI="},"Data":null,"Name":"","Next":null}]`
+	if string(data) != want {
+		t.Fatalf("got %s\nwant %s", data, want)
+	}
+}
+
+func TestReplyCycleError(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("cycle", func() *binaryNode {
+		n := &binaryNode{}
+		n.Next = n
+		return n
+	})
+
+	_, err := client.Call(context.Background(), ct, "cycle")
+	if err == nil {
+		t.Fatal("应收到错误回复")
+	}
+}
@@ -0,0 +1,147 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const (
+	SOURCE_GO = "go" // GO 注册的 handler
+	SOURCE_JS = "js" // 对端（JS）注册的 handler
+)
+
+// 内置通道：对端获取已注册的通道列表
+const CHANNEL_LIST = "__channels"
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// 已注册通道的描述
+type ChannelInfo struct {
+	Channel  string         // 通道名称
+	Source   string         // 注册方，见 SOURCE_*
+	Params   []reflect.Type // 调用方需要传入的参数类型，不含自动填充的 context.Context、*Stream。对端注册的通道为空
+	Variadic bool           // 最后一个参数是否为可变参数
+	Result   reflect.Type   // 返回值类型，流式通道为每条数据的类型，无返回值时为 nil
+	Stream   bool           // 是否为流式通道
+	Timeout  time.Duration  // 单独设置的超时时间，为 0 时使用默认值，小于 0 时不超时
+}
+
+// 转为 JSON 时，类型以 JS 的类型名称表示，供前端校验调用参数
+func (info ChannelInfo) MarshalJSON() ([]byte, error) {
+	params := make([]string, 0, len(info.Params))
+	for _, p := range info.Params {
+		params = append(params, jsTypeName(p))
+	}
+
+	result := ""
+	if info.Result != nil {
+		result = jsTypeName(info.Result)
+	}
+
+	return json.Marshal(struct {
+		Channel  string   `json:"channel"`
+		Source   string   `json:"source"`
+		Params   []string `json:"params"`
+		Variadic bool     `json:"variadic"`
+		Result   string   `json:"result,omitempty"`
+		Stream   bool     `json:"stream"`
+		Timeout  int64    `json:"timeout,omitempty"` // 毫秒
+	}{info.Channel, info.Source, params, info.Variadic, result, info.Stream, info.Timeout.Milliseconds()})
+}
+
+// 从 GO handler 的函数签名生成通道描述
+func newChannelInfo(channel string, handlerType reflect.Type) ChannelInfo {
+	info := ChannelInfo{
+		Channel:  channel,
+		Source:   SOURCE_GO,
+		Params:   []reflect.Type{},
+		Variadic: handlerType.IsVariadic(),
+	}
+
+	for i := 0; i < handlerType.NumIn(); i++ {
+		param := handlerType.In(i)
+		if param == contextType {
+			continue
+		}
+		if param == streamType {
+			info.Stream = true
+			continue
+		}
+		info.Params = append(info.Params, param)
+	}
+
+	if handlerType.NumOut() > 0 && handlerType.Out(0) != errorType {
+		out := handlerType.Out(0)
+		if out.Kind() == reflect.Chan {
+			info.Stream = true
+			out = out.Elem()
+		}
+		info.Result = out
+	}
+
+	return info
+}
+
+// JS 的类型名称
+func jsTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Ptr:
+		return jsTypeName(t.Elem())
+	default:
+		return "any"
+	}
+}
+
+// 获取所有已注册的通道，按名称排序
+func (r *Router) Channels() []ChannelInfo {
+	r.mu.RLock()
+	infos := make([]ChannelInfo, 0, len(r.channels))
+	for channel, info := range r.channels {
+		info.Timeout = r.timeouts[channel]
+		infos = append(infos, info)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Channel < infos[j].Channel
+	})
+
+	return infos
+}
+
+// 获取通道描述
+func (r *Router) GetChannel(channel string) (info ChannelInfo, exist bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, exist = r.channels[channel]
+	info.Timeout = r.timeouts[channel]
+	return
+}
+
+// 注册内置通道，对端可通过该通道获取通道列表
+func (r *Router) registerChannelsHandler() {
+	r.handlers[CHANNEL_LIST] = func(ctx context.Context, cb resultCallback, args ...interface{}) {
+		if cb != nil {
+			cb(r.Channels(), nil)
+		}
+	}
+}
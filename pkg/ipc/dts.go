@@ -0,0 +1,344 @@
+package ipc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epkgs/blink/internal/cast"
+)
+
+// 以 blink_dts 标签构建时，blink 的 KeepRunning 会将已注册通道的 TypeScript 声明写入此环境变量指定的文件并退出程序，供 cmd/blink-dts 使用
+//
+// 未使用该标签构建的程序不会读取此环境变量
+const ENV_DTS_OUT = "BLINK_DTS_OUT"
+
+// cmd/blink-dts 构建目标程序时使用的标签
+const TAG_DTS = "blink_dts"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// 生成已注册的 GO 通道的 TypeScript 声明（.d.ts）
+//
+// 声明包含每个通道的 ipc.invoke/ipc.invokeWithTimeout/ipc.sent（流式通道为 ipc.stream）重载，以及参数、返回值里用到的结构体
+func (r *Router) GenerateTypeScript(w io.Writer) error {
+	g := &dtsGenerator{
+		names:  map[reflect.Type]string{},
+		used:   map[string]reflect.Type{},
+		shapes: map[string]string{},
+	}
+
+	var invokes, timeouts, sents, streams, services []string
+
+	for _, info := range r.Channels() {
+		if info.Source != SOURCE_GO {
+			continue
+		}
+
+		params := g.params(info)
+
+		if info.Stream {
+			streams = append(streams, fmt.Sprintf("stream(channel: %q%s): IPCStream<%s>;", info.Channel, params, g.resultOf(info)))
+			continue
+		}
+
+		invokes = append(invokes, fmt.Sprintf("(channel: %q%s): IPCPromise<%s>;", info.Channel, params, g.resultOf(info)))
+		timeouts = append(timeouts, fmt.Sprintf("invokeWithTimeout(timeout: number, channel: %q%s): IPCPromise<%s>;", info.Channel, params, g.resultOf(info)))
+		sents = append(sents, fmt.Sprintf("sent(channel: %q%s): void;", info.Channel, params))
+	}
+
+	// 通过 Expose 暴露的 GO 对象
+	for _, service := range r.Services() {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "const %s: {\n", dtsIdent(service.Name))
+		for _, method := range service.Methods {
+			info, exist := r.GetChannel(method.Channel)
+			if !exist {
+				continue
+			}
+			if method.Stream {
+				fmt.Fprintf(&sb, "    %s(%s): IPCStream<%s>;\n", dtsKey(method.Name), strings.TrimPrefix(g.params(info), ", "), g.resultOf(info))
+			} else {
+				fmt.Fprintf(&sb, "    %s(%s): IPCPromise<%s>;\n", dtsKey(method.Name), strings.TrimPrefix(g.params(info), ", "), g.resultOf(info))
+			}
+		}
+		sb.WriteString("};")
+		services = append(services, sb.String())
+	}
+
+	buf := &bytes.Buffer{}
+
+	buf.WriteString("// Code generated by blink. DO NOT EDIT.\n\n")
+
+	names := make([]string, 0, len(g.shapes))
+	for name := range g.shapes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(buf, "export interface %s %s\n\n", name, g.shapes[name])
+	}
+
+	buf.WriteString(dtsIPCStream)
+
+	buf.WriteString("export interface IPCInvokeFn {\n")
+	for _, line := range invokes {
+		fmt.Fprintf(buf, "    %s\n", dtsIndent(line))
+	}
+	buf.WriteString(dtsIPCInvokeFallback)
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(dtsIPCInvoke)
+
+	buf.WriteString("export interface IPC {\n")
+	buf.WriteString("    invoke: IPCInvoke;\n")
+	for _, lines := range [][]string{timeouts, sents, streams} {
+		for _, line := range lines {
+			fmt.Fprintf(buf, "    %s\n", dtsIndent(line))
+		}
+	}
+	buf.WriteString(dtsIPCFallback)
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(dtsGlobal)
+	for _, service := range services {
+		fmt.Fprintf(buf, "    %s\n", dtsIndent(service))
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// 写入 TypeScript 声明文件
+func (r *Router) WriteTypeScriptFile(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.GenerateTypeScript(f)
+}
+
+const dtsIPCStream = `export interface IPCStream<T> extends AsyncIterable<T> {
+    onData(cb: (data: T) => void | Promise<void>): IPCStream<T>;
+    onEnd(cb: (result?: any) => void): IPCStream<T>;
+    onError(cb: (err: Error) => void): IPCStream<T>;
+    cancel(): void;
+}
+
+export interface IPCError extends Error {
+    code?: string;
+    data?: any;
+}
+
+export interface IPCChannelInfo {
+    channel: string;
+    source: 'go' | 'js';
+    params: string[];
+    variadic: boolean;
+    result?: string;
+    stream: boolean;
+    timeout?: number;
+}
+
+export interface IPCPromise<T> extends Promise<T> {
+    cancel(): void;
+}
+
+export interface IPCInvokeOptions {
+    timeout?: number;
+    signal?: AbortSignal;
+}
+
+`
+
+const dtsIPCInvokeFallback = `    (channel: string, ...args: any[]): IPCPromise<any>;
+`
+
+const dtsIPCInvoke = `export interface IPCInvoke extends IPCInvokeFn {
+    withOptions(options: IPCInvokeOptions): IPCInvokeFn;
+}
+
+`
+
+const dtsIPCFallback = `    invokeWithTimeout(timeout: number, channel: string, ...args: any[]): IPCPromise<any>;
+    sent(channel: string, ...args: any[]): void;
+    stream(channel: string, ...args: any[]): IPCStream<any>;
+    handle(channel: string, handler: (...args: any[]) => any): void;
+    channels(): Promise<IPCChannelInfo[]>;
+    on(pattern: string, cb: (payload: any, topic: string) => void): () => void;
+    emit(topic: string, payload?: any): void;
+    IPCError: new (message: string, options?: { code?: string; data?: any; stack?: string }) => IPCError;
+`
+
+const dtsGlobal = `declare global {
+    interface Window {
+        ipc: IPC;
+    }
+    const ipc: IPC;
+`
+
+type dtsGenerator struct {
+	names  map[reflect.Type]string // 结构体类型 -> interface 名称
+	used   map[string]reflect.Type // interface 名称 -> 结构体类型，用于处理重名
+	shapes map[string]string       // interface 名称 -> 声明内容
+}
+
+// 参数列表，以 ", " 开头
+func (g *dtsGenerator) params(info ChannelInfo) string {
+	var sb strings.Builder
+	for i, p := range info.Params {
+		if info.Variadic && i == len(info.Params)-1 {
+			fmt.Fprintf(&sb, ", ...args: %s[]", g.wrap(g.typeOf(p.Elem())))
+			continue
+		}
+		fmt.Fprintf(&sb, ", arg%d: %s", i, g.typeOf(p))
+	}
+	return sb.String()
+}
+
+func (g *dtsGenerator) resultOf(info ChannelInfo) string {
+	if info.Result == nil {
+		return "void"
+	}
+	return g.typeOf(info.Result)
+}
+
+// 联合类型作为数组元素时需要加括号
+func (g *dtsGenerator) wrap(ts string) string {
+	if strings.Contains(ts, " | ") {
+		return "(" + ts + ")"
+	}
+	return ts
+}
+
+func (g *dtsGenerator) typeOf(t reflect.Type) string {
+
+	if t == timeType {
+		return "string"
+	}
+
+	if t.Implements(cast.JSONMarshalerType) || reflect.PtrTo(t).Implements(cast.JSONMarshalerType) {
+		return "any"
+	}
+
+	if t.Implements(cast.TextMarshalerType) || reflect.PtrTo(t).Implements(cast.TextMarshalerType) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "Uint8Array" // 以二进制信封传递，见 Binary
+		}
+		return g.wrap(g.typeOf(t.Elem())) + "[]"
+	case reflect.Array:
+		return g.wrap(g.typeOf(t.Elem())) + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s>", g.typeOf(t.Elem()))
+	case reflect.Ptr:
+		return g.typeOf(t.Elem()) + " | null"
+	case reflect.Struct:
+		return g.structOf(t)
+	default:
+		return "any"
+	}
+}
+
+func (g *dtsGenerator) structOf(t reflect.Type) string {
+
+	// 匿名结构体直接内联
+	if t.Name() == "" {
+		return g.shapeOf(t)
+	}
+
+	if name, exist := g.names[t]; exist {
+		return name
+	}
+
+	name := dtsIdent(t.Name())
+	if other, exist := g.used[name]; exist && other != t {
+		name = dtsIdent(path.Base(t.PkgPath())) + name
+	}
+	for i := 2; ; i++ {
+		if other, exist := g.used[name]; !exist || other == t {
+			break
+		}
+		name = fmt.Sprintf("%s%d", dtsIdent(t.Name()), i)
+	}
+
+	// 先登记名称，避免递归引用时死循环
+	g.names[t] = name
+	g.used[name] = t
+	g.shapes[name] = g.shapeOf(t)
+
+	return name
+}
+
+func (g *dtsGenerator) shapeOf(t reflect.Type) string {
+	fields := cast.JSONFields(t)
+	if len(fields) == 0 {
+		return "{}"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, f := range fields {
+		ts := g.typeOf(f.Type)
+		if f.Quoted {
+			ts = "string"
+		}
+
+		optional := ""
+		if f.OmitEmpty {
+			optional = "?"
+		}
+
+		// 内联的匿名结构体需要多缩进一级
+		ts = strings.ReplaceAll(ts, "\n", "\n    ")
+
+		fmt.Fprintf(&sb, "    %s%s: %s;\n", dtsKey(f.Name), optional, ts)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// 多行声明（如内联的匿名结构体）的后续行多缩进一级
+func dtsIndent(s string) string {
+	return strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+// 转为合法的 TypeScript 标识符（如泛型实例化后的类型名称）
+func dtsIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '$' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// 属性名不是合法标识符时加引号
+func dtsKey(name string) string {
+	if name != "" && dtsIdent(name) == name && !(name[0] >= '0' && name[0] <= '9') {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
@@ -0,0 +1,76 @@
+package ipc
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "更新 testdata 下的期望输出")
+
+type dtsUser struct {
+	ID      int64               `json:"id,string"`
+	Name    string              `json:"name"`
+	Age     int                 `json:"age,omitempty"`
+	Born    time.Time           `json:"born"`
+	Avatar  []byte              `json:"avatar"`
+	Tags    map[string][]string `json:"tags"`
+	Manager *dtsUser            `json:"manager"`
+	Extra   struct {
+		Note string `json:"note"`
+	} `json:"extra"`
+	Header string `json:"x-header"`
+	skip   string
+}
+
+type dtsItem struct {
+	Values []*int `json:"values"`
+}
+
+// 参数为包级别的 dtsItem，与测试函数中的同名类型冲突
+func dtsFirstItem(items []dtsItem) *dtsItem { return nil }
+
+type dtsFiles struct{}
+
+func (dtsFiles) List(dir string) ([]string, error)                           { return nil, nil }
+func (dtsFiles) Sum(nums ...int) int                                         { return 0 }
+func (dtsFiles) Watch(ctx context.Context, stream *Stream, dir string) error { return nil }
+
+func TestGenerateTypeScript(t *testing.T) {
+	// 与 dtsItem 重名的类型
+	type dtsItem struct {
+		Name string `json:"name"`
+	}
+
+	r := NewRouter()
+	r.Handle("user.get", func(ctx context.Context, id int64) (*dtsUser, error) { return nil, nil })
+	r.Handle("user.save", func(user dtsUser, tags ...string) error { return nil })
+	r.Handle("item.first", dtsFirstItem)
+	r.Handle("item.pair", func(a struct{ Item dtsItem }, b dtsItem) []interface{} { return nil })
+	r.Handle("tick", func(n int) <-chan time.Time { return nil })
+	r.Expose("files", dtsFiles{})
+
+	var buf bytes.Buffer
+	if err := r.GenerateTypeScript(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "ipc.d.ts.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("生成的声明与 %s 不一致，使用 -update 更新：\n%s", golden, buf.String())
+	}
+}
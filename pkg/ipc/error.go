@@ -0,0 +1,85 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// 内置的错误码
+const (
+	ERR_NOT_FOUND = "E_NOT_FOUND" // 通道不存在
+	ERR_ARGUMENT  = "E_ARGUMENT"  // 参数错误
+	ERR_TIMEOUT   = "E_TIMEOUT"   // 调用超时
+	ERR_CANCELED  = "E_CANCELED"  // 调用被取消
+	ERR_FORBIDDEN = "E_FORBIDDEN" // 调用来源不被允许
+)
+
+// 可携带错误码和附加数据的错误
+//
+// GO handler 返回实现了此接口的错误时，对端收到的错误会带上对应的 code 和 data
+type CodedError interface {
+	error
+	IPCError() (code string, data interface{})
+}
+
+// 在 GO 与对端之间传递的错误
+//
+// 对端 reject / throw 的错误会转为 *Error，可通过 errors.As 获取
+type Error struct {
+	Code    string      `json:"code,omitempty"`  // 错误码
+	Message string      `json:"message"`         // 错误信息
+	Data    interface{} `json:"data,omitempty"`  // 附加数据
+	Stack   string      `json:"stack,omitempty"` // 调用栈，仅 JS 端的错误有
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) IPCError() (code string, data interface{}) {
+	return e.Code, e.Data
+}
+
+// 兼容仅有错误信息的字符串
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		*e = Error{Message: msg}
+		return nil
+	}
+
+	type payload Error
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	*e = Error(p)
+	return nil
+}
+
+// 将 error 转为可传递给对端的 *Error
+func ToError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+
+	e := &Error{Message: err.Error()}
+
+	var coded CodedError
+	switch {
+	case errors.As(err, &coded):
+		e.Code, e.Data = coded.IPCError()
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code = ERR_TIMEOUT
+	case errors.Is(err, context.Canceled):
+		e.Code = ERR_CANCELED
+	}
+
+	return e
+}
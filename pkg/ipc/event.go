@@ -0,0 +1,268 @@
+package ipc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 事件回调
+type EventHandler func(topic string, payload interface{})
+
+type subscriber struct {
+	id      int
+	pattern string
+	fn      EventHandler
+}
+
+// 事件总线，topic 以 . 分隔，订阅时可使用通配符，见 MatchTopic
+type eventBus struct {
+	mu       sync.RWMutex
+	nextID   int
+	locals   []*subscriber                     // GO 的订阅，按订阅顺序
+	peers    map[Transport]map[string]struct{} // 对端订阅的 pattern
+	retained map[string]interface{}            // 保留的最新事件，topic -> payload
+
+	// 对端发布的事件，按顺序分发。队列不设上限，入队不会阻塞 Receive（可能在 UI 线程中调用）
+	queueMu sync.Mutex
+	queue   []func()
+	wake    chan struct{}
+	once    sync.Once
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		peers:    make(map[Transport]map[string]struct{}),
+		retained: make(map[string]interface{}),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (b *eventBus) enqueue(job func()) {
+	b.once.Do(func() {
+		go b.dispatch()
+	})
+
+	b.queueMu.Lock()
+	b.queue = append(b.queue, job)
+	b.queueMu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 按入队顺序逐个执行
+func (b *eventBus) dispatch() {
+	for range b.wake {
+		for {
+			b.queueMu.Lock()
+			if len(b.queue) == 0 {
+				b.queueMu.Unlock()
+				break
+			}
+			job := b.queue[0]
+			b.queue[0] = nil
+			b.queue = b.queue[1:]
+			b.queueMu.Unlock()
+
+			job()
+		}
+	}
+}
+
+// 判断 topic 是否匹配 pattern
+//
+// topic 以 . 分隔为多段，pattern 中 * 匹配一段，** 匹配任意段（包括零段），如：
+//
+//	settings.*      匹配 settings.theme，不匹配 settings.window.size
+//	download.**     匹配 download、download.progress、download.a.b
+func MatchTopic(pattern, topic string) bool {
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchSegments(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(topic); i++ {
+				if matchSegments(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(topic) == 0 || (pattern[0] != "*" && pattern[0] != topic[0]) {
+			return false
+		}
+
+		pattern, topic = pattern[1:], topic[1:]
+	}
+
+	return len(topic) == 0
+}
+
+// 订阅事件，包括 GO 和对端发布的事件，返回取消订阅的函数
+//
+// 已保留（EmitRetained）且匹配 pattern 的事件会立即回调
+func (r *Router) On(pattern string, fn EventHandler) (off func()) {
+	b := r.events
+
+	b.mu.Lock()
+	b.nextID++
+	sub := &subscriber{id: b.nextID, pattern: pattern, fn: fn}
+	b.locals = append(b.locals, sub)
+	retained := b.retainedOf(pattern)
+	b.mu.Unlock()
+
+	for topic, payload := range retained {
+		callSubscriber(sub, topic, payload)
+	}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, s := range b.locals {
+			if s.id == sub.id {
+				b.locals = append(b.locals[:i:i], b.locals[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// 发布事件到 GO 以及所有订阅了该 topic 的对端
+func (r *Router) Emit(topic string, payload interface{}) {
+	r.publish(nil, topic, payload)
+}
+
+// 发布事件，并保留为该 topic 的最新事件，之后订阅的 GO 或对端（如新建的 View）会立即收到
+//
+// payload 为 nil 时清除保留的事件
+func (r *Router) EmitRetained(topic string, payload interface{}) {
+	b := r.events
+
+	b.mu.Lock()
+	if payload == nil {
+		delete(b.retained, topic)
+	} else {
+		b.retained[topic] = payload
+	}
+	b.mu.Unlock()
+
+	if payload != nil {
+		r.publish(nil, topic, payload)
+	}
+}
+
+// 分发事件，from 为发布事件的对端，不会再发回给它
+func (r *Router) publish(from Transport, topic string, payload interface{}) {
+	b := r.events
+
+	b.mu.RLock()
+	var locals []*subscriber
+	for _, sub := range b.locals {
+		if MatchTopic(sub.pattern, topic) {
+			locals = append(locals, sub)
+		}
+	}
+
+	var peers []Transport
+	for t, patterns := range b.peers {
+		if t == from {
+			continue
+		}
+		for pattern := range patterns {
+			if MatchTopic(pattern, topic) {
+				peers = append(peers, t)
+				break
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, t := range peers {
+		_ = r.sendEvent(t, topic, payload)
+	}
+
+	for _, sub := range locals {
+		callSubscriber(sub, topic, payload)
+	}
+}
+
+func callSubscriber(sub *subscriber, topic string, payload interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("panic by ipc event[ %v ]: %v", topic, fmt.Sprint(r))
+		}
+	}()
+
+	sub.fn(topic, payload)
+}
+
+func (r *Router) sendEvent(t Transport, topic string, payload interface{}) error {
+	return r.send(t, Message{
+		Type:    TYPE_EVENT,
+		Channel: topic,
+		Args:    []interface{}{payload},
+	})
+}
+
+// 对端的事件消息：订阅、取消订阅、发布
+func (r *Router) handleEventMessage(t Transport, msg *Message) {
+	b := r.events
+
+	switch msg.Type {
+	case TYPE_SUB:
+		b.mu.Lock()
+		patterns, exist := b.peers[t]
+		if !exist {
+			patterns = make(map[string]struct{})
+			b.peers[t] = patterns
+		}
+		patterns[msg.Channel] = struct{}{}
+		retained := b.retainedOf(msg.Channel)
+		b.mu.Unlock()
+
+		for topic, payload := range retained {
+			_ = r.sendEvent(t, topic, payload)
+		}
+
+	case TYPE_UNSUB:
+		b.mu.Lock()
+		delete(b.peers[t], msg.Channel)
+		if len(b.peers[t]) == 0 {
+			delete(b.peers, t)
+		}
+		b.mu.Unlock()
+
+	case TYPE_EVENT:
+		var payload interface{}
+		if len(msg.Args) > 0 {
+			payload = msg.Args[0]
+		}
+		r.publish(t, msg.Channel, payload)
+	}
+}
+
+// 移除对端的所有订阅
+func (b *eventBus) removePeer(t Transport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.peers, t)
+}
+
+// 匹配 pattern 的保留事件，须持有锁
+func (b *eventBus) retainedOf(pattern string) map[string]interface{} {
+	retained := map[string]interface{}{}
+	for topic, payload := range b.retained {
+		if MatchTopic(pattern, topic) {
+			retained[topic] = payload
+		}
+	}
+	return retained
+}
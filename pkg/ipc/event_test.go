@@ -0,0 +1,99 @@
+package ipc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// 订阅回调阻塞时，对端发布的事件不能阻塞 Receive，且按顺序分发
+func TestEventReceiveNeverBlocks(t *testing.T) {
+	r := NewRouter()
+	ct, st := NewMemoryTransport()
+	defer ct.Close()
+
+	const count = 1000
+
+	release := make(chan struct{})
+	got := make(chan float64, count)
+	r.On("progress", func(topic string, payload interface{}) {
+		<-release
+		got <- payload.(float64)
+	})
+
+	received := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			data, _ := json.Marshal(Message{Type: TYPE_EVENT, Channel: "progress", Args: []interface{}{i}})
+			r.Receive(st, string(data))
+		}
+		close(received)
+	}()
+
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Receive 被阻塞")
+	}
+
+	close(release)
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-got:
+			if int(n) != i {
+				t.Fatalf("第 %d 个事件为 %v", i, n)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("只收到 %d 个事件", i)
+		}
+	}
+}
+
+// 阻塞发送的传输，用于检查 Receive 中是否发送了消息
+type blockingTransport struct {
+	release chan struct{}
+	sent    chan string
+}
+
+func (t *blockingTransport) Send(data string) error {
+	<-t.release
+	t.sent <- data
+	return nil
+}
+
+func (t *blockingTransport) OnReceive(fn func(data string)) {}
+
+// 对端订阅时，保留事件在事件队列中回放，不在 Receive 中发送
+func TestEventRetainedReplayOutsideReceive(t *testing.T) {
+	r := NewRouter()
+	r.EmitRetained("state", "ready")
+
+	bt := &blockingTransport{release: make(chan struct{}), sent: make(chan string, 1)}
+
+	received := make(chan struct{})
+	go func() {
+		data, _ := json.Marshal(Message{Type: TYPE_SUB, Channel: "state"})
+		r.Receive(bt, string(data))
+		close(received)
+	}()
+
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Receive 中回放了保留事件")
+	}
+
+	close(bt.release)
+	select {
+	case data := <-bt.sent:
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != TYPE_EVENT || msg.Channel != "state" || len(msg.Args) != 1 || msg.Args[0] != "ready" {
+			t.Fatalf("回放的事件为 %s", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未回放保留事件")
+	}
+}
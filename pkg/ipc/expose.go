@@ -0,0 +1,93 @@
+package ipc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode"
+)
+
+// 通过 Expose 暴露的 GO 对象
+type ServiceInfo struct {
+	Name    string          `json:"name"`
+	Methods []ServiceMethod `json:"methods"`
+}
+
+// 暴露的方法
+type ServiceMethod struct {
+	Name    string `json:"name"`    // 对端使用的方法名，即 GO 方法名的首字母小写形式
+	Channel string `json:"channel"` // 对应的通道：<服务名>.<方法名>
+	Stream  bool   `json:"stream"`  // 是否为流式通道
+}
+
+// 将 GO 对象的导出方法注册为通道，通道名称为 <name>.<方法名首字母小写>，如 files.list
+//
+// 每个方法按 Handle 的规则注册，opts 对所有方法生效。同名的服务再次暴露时，会覆盖同名的方法
+func (r *Router) Expose(name string, obj interface{}, opts ...HandleOption) {
+	if name == "" {
+		panic("ipc expose: name is empty")
+	}
+
+	objVal := reflect.ValueOf(obj)
+	if !objVal.IsValid() {
+		panic(fmt.Sprintf("ipc expose %s: object is nil", name))
+	}
+
+	objType := objVal.Type()
+	if objType.NumMethod() == 0 {
+		panic(fmt.Sprintf("ipc expose %s: %s has no exported methods", name, objType))
+	}
+
+	service := ServiceInfo{Name: name}
+
+	for i := 0; i < objType.NumMethod(); i++ {
+		method := objType.Method(i)
+		if method.PkgPath != "" {
+			continue // 接口类型会包含未导出的方法
+		}
+
+		channel := name + "." + lowerCamel(method.Name)
+		r.Handle(channel, objVal.Method(i).Interface(), opts...)
+
+		info, _ := r.GetChannel(channel)
+		service.Methods = append(service.Methods, ServiceMethod{
+			Name:    lowerCamel(method.Name),
+			Channel: channel,
+			Stream:  info.Stream,
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.services[name] = service
+}
+
+// 已暴露的服务，按名称排序
+func (r *Router) Services() []ServiceInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]ServiceInfo, 0, len(r.services))
+	for _, service := range r.services {
+		list = append(list, service)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// 首字母小写，开头的连续大写（缩写）一并转为小写，如 List -> list，URLPath -> urlPath，ID -> id
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		// 缩写后紧跟小写字母时，最后一个大写字母属于下一个单词
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,133 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLowerCamel(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"List", "list"},
+		{"list", "list"},
+		{"GetUser", "getUser"},
+		{"ID", "id"},
+		{"URLPath", "urlPath"},
+		{"HTTPServer2", "httpServer2"},
+		{"A", "a"},
+		{"ÉTé", "éTé"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := lowerCamel(tt.name); got != tt.want {
+			t.Errorf("lowerCamel(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+type exposeFiles struct {
+	prefix string
+}
+
+func (f *exposeFiles) List(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, errors.New("dir is empty")
+	}
+	return []string{f.prefix + dir + "/a", f.prefix + dir + "/b"}, nil
+}
+
+func (f *exposeFiles) ReadURL(ctx context.Context, url string) string {
+	return f.prefix + url
+}
+
+func (f *exposeFiles) Watch(ctx context.Context, dir string) <-chan string {
+	ch := make(chan string, 1)
+	ch <- f.prefix + dir
+	close(ch)
+	return ch
+}
+
+func (f *exposeFiles) hidden() {}
+
+func TestExpose(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Expose("files", &exposeFiles{prefix: "/"})
+
+	services := server.Services()
+	want := []ServiceInfo{{
+		Name: "files",
+		Methods: []ServiceMethod{
+			{Name: "list", Channel: "files.list"},
+			{Name: "readURL", Channel: "files.readURL"},
+			{Name: "watch", Channel: "files.watch", Stream: true},
+		},
+	}}
+	if !reflect.DeepEqual(services, want) {
+		t.Fatalf("services = %+v, want %+v", services, want)
+	}
+
+	// 对端通过 <服务名>.<方法名> 调用，方法绑定到暴露的对象
+	result, err := client.Call(context.Background(), ct, "files.list", "tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"/tmp/a", "/tmp/b"}; !reflect.DeepEqual(result, want) {
+		t.Fatalf("result = %#v, want %#v", result, want)
+	}
+
+	if result, err := client.Call(context.Background(), ct, "files.readURL", "x"); err != nil || result != "/x" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+
+	if _, err := client.Call(context.Background(), ct, "files.list", ""); err == nil || err.Error() != "dir is empty" {
+		t.Fatalf("err = %v", err)
+	}
+
+	if _, exist := server.GetChannel("files.hidden"); exist {
+		t.Fatal("未导出的方法不应注册")
+	}
+
+	// 同名服务再次暴露时覆盖同名方法，opts 对所有方法生效
+	server.Expose("files", &exposeFiles{prefix: "~"}, WithOrigins("https://app.example.com"))
+	_, err = client.Call(context.Background(), ct, "files.readURL", "x")
+	if e := asError(t, err); e.Code != ERR_FORBIDDEN {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_FORBIDDEN)
+	}
+	if result, err := server.Invoke("files.readURL", "x"); err != nil || result != "~x" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+
+	server.Expose("dirs", &exposeFiles{})
+	if services := server.Services(); len(services) != 2 || services[0].Name != "dirs" || services[1].Name != "files" {
+		t.Fatalf("services = %+v", services)
+	}
+}
+
+func TestExposeInvalid(t *testing.T) {
+	r := NewRouter()
+
+	for _, tt := range []struct {
+		name    string
+		service string
+		obj     interface{}
+	}{
+		{"空名称", "", &exposeFiles{}},
+		{"nil", "files", nil},
+		{"无导出方法", "files", struct{}{}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: 应 panic", tt.name)
+				}
+			}()
+			r.Expose(tt.service, tt.obj)
+		}()
+	}
+}
@@ -0,0 +1,57 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 对端取消了调用
+var errPeerCanceled = errors.New("ipc: 对端已取消调用")
+
+// 对端发起、正在执行的调用，收到取消消息或对端断开时取消 handler 的 ctx
+type invocations struct {
+	mu      sync.Mutex
+	entries map[peerKey]context.CancelCauseFunc
+}
+
+func newInvocations() *invocations {
+	return &invocations{
+		entries: make(map[peerKey]context.CancelCauseFunc),
+	}
+}
+
+func (s *invocations) Add(t Transport, id string, cancel context.CancelCauseFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[peerKey{t, id}] = cancel
+}
+
+func (s *invocations) Del(t Transport, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, peerKey{t, id})
+}
+
+// 取消对端发起的某个调用
+func (s *invocations) Cancel(t Transport, id string) bool {
+	s.mu.Lock()
+	cancel, exist := s.entries[peerKey{t, id}]
+	s.mu.Unlock()
+
+	if exist {
+		cancel(errPeerCanceled)
+	}
+	return exist
+}
+
+// 取消由 t 发起的所有调用
+func (s *invocations) CancelBy(t Transport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, cancel := range s.entries {
+		if key.transport == t {
+			cancel(errPeerCanceled)
+		}
+	}
+}
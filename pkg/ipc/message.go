@@ -0,0 +1,28 @@
+package ipc
+
+// 消息类型，为空时为普通的 invoke/sent/reply 消息
+const (
+	TYPE_STREAM = "stream" // 发起流式调用
+	TYPE_CHUNK  = "chunk"  // 流数据
+	TYPE_END    = "end"    // 流结束（可能带有错误）
+	TYPE_CANCEL = "cancel" // 取消流或调用，ReplyId 为被取消的消息 ID
+	TYPE_ACK    = "ack"    // 已消费一条流数据
+	TYPE_EVENT  = "event"  // 发布事件，Channel 为 topic，Args[0] 为 payload
+	TYPE_SUB    = "sub"    // 订阅事件，Channel 为 pattern
+	TYPE_UNSUB  = "unsub"  // 取消订阅，Channel 为 pattern
+	TYPE_PART   = "part"   // 消息分片，见 Part
+)
+
+// 在 GO 与对端之间传递的消息
+type Message struct {
+	ID      string        `json:"id"`                // 消息 ID
+	ReplyId string        `json:"replyId"`           // 回复ID
+	Channel string        `json:"channel"`           // 通道
+	Args    []interface{} `json:"args"`              // 参数
+	Result  interface{}   `json:"result,omitempty"`  // 返回值，当有回复ID时，此字段有效
+	Error   *Error        `json:"error,omitempty"`   // 是否错误，当有回复ID时，此字段有效
+	Type    string        `json:"type,omitempty"`    // 消息类型，为空时为普通消息，其他见 TYPE_*
+	Timeout int64         `json:"timeout,omitempty"` // 调用方指定的超时时间（毫秒），为 0 时使用通道的超时时间，小于 0 时不超时
+	Part    *Part         `json:"part,omitempty"`    // 消息分片，Type 为 TYPE_PART 时有效
+	Nonce   string        `json:"nonce,omitempty"`   // 对端的身份凭证，由 Transport 的实现在交给 Router 之前校验
+}
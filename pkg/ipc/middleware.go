@@ -0,0 +1,82 @@
+package ipc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 一次通道调用，由中间件按注册顺序处理
+type Call struct {
+	Context   context.Context
+	Channel   string
+	Args      []interface{}
+	Transport Transport // 发起调用的对端，GO 直接调用时为 nil
+	Notify    bool      // 是否为无须返回值的调用（Sent），此时 next 的返回值无意义
+}
+
+// 执行后续的中间件以及 handler
+type Next func(call *Call) (interface{}, error)
+
+// 中间件
+//
+// 可在调用 next 前后进行鉴权、记录日志、统计耗时等；不调用 next 直接返回，即可拦截本次调用
+type Middleware func(call *Call, next Next) (interface{}, error)
+
+// 添加中间件，对所有经过 Invoke、Sent 以及对端发起的通道调用生效
+func (r *Router) Use(middlewares ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// 经过中间件后调用通道的 handler
+func (r *Router) dispatch(call *Call) (result interface{}, err error) {
+
+	r.mu.RLock()
+	middlewares := r.middlewares
+	r.mu.RUnlock()
+
+	next := Next(func(call *Call) (interface{}, error) {
+		h, err := r.lookup(call.Channel)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := r.checkOrigin(call); err != nil {
+			return nil, err
+		}
+
+		if call.Notify {
+			h(call.Context, nil, call.Args...)
+			return nil, nil
+		}
+
+		return await(call.Context, func(ctx context.Context, cb resultCallback) {
+			h(ctx, cb, call.Args...)
+		})
+	})
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, n := middlewares[i], next
+		next = func(call *Call) (interface{}, error) {
+			return middleware(call, n)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			err, ok = r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			log.Error("panic by ipc middleware[ %v ]: %v", call.Channel, err)
+			result = nil
+		}
+	}()
+
+	return next(call)
+}
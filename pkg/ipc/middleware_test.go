@@ -0,0 +1,175 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type middlewareCtxKey struct{}
+
+// 按注册顺序进入、逆序返回
+func TestMiddlewareOrder(t *testing.T) {
+	r := NewRouter()
+
+	var mu sync.Mutex
+	var trace []string
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		trace = append(trace, s)
+	}
+
+	tracer := func(name string) Middleware {
+		return func(call *Call, next Next) (interface{}, error) {
+			record(name + ">")
+			result, err := next(call)
+			record("<" + name)
+			return result, err
+		}
+	}
+
+	r.Use(tracer("a"), tracer("b"))
+	r.Use(tracer("c"))
+
+	r.Handle("ping", func() string {
+		record("handler")
+		return "pong"
+	})
+
+	result, err := r.Invoke("ping")
+	if err != nil || result != "pong" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+
+	want := []string{"a>", "b>", "c>", "handler", "<c", "<b", "<a"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("trace = %v, want %v", trace, want)
+	}
+}
+
+// 不调用 next 即拦截调用，handler 不会执行
+func TestMiddlewareShortCircuit(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	called := false
+	server.Handle("secret", func() string {
+		called = true
+		return "secret"
+	})
+
+	server.Use(func(call *Call, next Next) (interface{}, error) {
+		switch call.Args[0] {
+		case "cached":
+			return "cached", nil
+		case "deny":
+			return nil, &Error{Code: "E_DENIED", Message: "denied"}
+		}
+		return next(call)
+	})
+
+	result, err := client.Call(context.Background(), ct, "secret", "cached")
+	if err != nil || result != "cached" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+
+	_, err = client.Call(context.Background(), ct, "secret", "deny")
+	if e := asError(t, err); e.Code != "E_DENIED" || e.Message != "denied" {
+		t.Fatalf("err = %+v", e)
+	}
+
+	if called {
+		t.Fatal("被拦截的调用不应执行 handler")
+	}
+
+	result, err = client.Call(context.Background(), ct, "secret", "pass")
+	if err != nil || result != "secret" || !called {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+}
+
+// 中间件可修改 ctx、参数，handler 的返回值与错误经过中间件
+func TestMiddlewarePropagation(t *testing.T) {
+	client, ct, server, st := newRouterPair(t)
+
+	server.Handle("greet", func(ctx context.Context, name string) (string, error) {
+		if name == "" {
+			return "", codedErr{}
+		}
+		prefix, _ := ctx.Value(middlewareCtxKey{}).(string)
+		return prefix + name, nil
+	})
+
+	var handlerErr error
+	var transports []Transport
+	server.Use(func(call *Call, next Next) (interface{}, error) {
+		transports = append(transports, call.Transport)
+
+		call.Context = context.WithValue(call.Context, middlewareCtxKey{}, "hi ")
+		if len(call.Args) == 0 {
+			call.Args = []interface{}{"anonymous"}
+		}
+
+		result, err := next(call)
+		if err != nil {
+			handlerErr = err
+		}
+		return result, err
+	})
+
+	result, err := client.Call(context.Background(), ct, "greet")
+	if err != nil || result != "hi anonymous" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+
+	result, err = server.Invoke("greet", "go")
+	if err != nil || result != "hi go" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+
+	_, err = client.Call(context.Background(), ct, "greet", "")
+	if e := asError(t, err); e.Code != "E_CUSTOM" {
+		t.Fatalf("code = %q, want E_CUSTOM", e.Code)
+	}
+	if !errors.As(handlerErr, &codedErr{}) {
+		t.Fatalf("中间件收到的错误 = %#v", handlerErr)
+	}
+
+	// 对端发起的调用带有对端的 Transport，GO 直接调用时为 nil
+	if want := []Transport{st, nil, st}; !reflect.DeepEqual(transports, want) {
+		t.Fatalf("transports = %v, want %v", transports, want)
+	}
+}
+
+// Sent 同样经过中间件，中间件的 panic 转为错误返回
+func TestMiddlewareNotifyAndPanic(t *testing.T) {
+	r := NewRouter()
+
+	done := make(chan string, 1)
+	r.Handle("log", func(s string) {
+		done <- s
+	})
+
+	var notify bool
+	r.Use(func(call *Call, next Next) (interface{}, error) {
+		if call.Channel == "boom" {
+			panic("middleware boom")
+		}
+		notify = call.Notify
+		return next(call)
+	})
+
+	if err := r.Sent("log", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-done; s != "x" || !notify {
+		t.Fatalf("s = %q, notify = %v", s, notify)
+	}
+
+	_, err := r.Invoke("boom")
+	if err == nil || err.Error() != "middleware boom" {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package ipc
+
+import "time"
+
+type handleOptions struct {
+	timeout    time.Duration
+	hasTimeout bool
+	origins    []string
+	strict     *bool // 为 nil 时使用 Router 的设置
+}
+
+// 注册 handler 时的选项
+type HandleOption func(*handleOptions)
+
+// 单独设置通道的超时时间，d <= 0 时不超时，同 Router.SetChannelTimeout
+func WithTimeout(d time.Duration) HandleOption {
+	return func(o *handleOptions) {
+		o.timeout = d
+		o.hasTimeout = true
+	}
+}
+
+// 单独设置通道是否严格转换参数，同 Router.SetStrictArgs
+func WithStrictArgs(strict bool) HandleOption {
+	return func(o *handleOptions) {
+		o.strict = &strict
+	}
+}
+
+// 限制只有来源匹配 patterns 之一的对端才能调用该通道，GO 直接调用不受限制
+//
+// 来源为对端页面 URL 的 origin（scheme://host[:port]），pattern 中的 * 匹配任意字符，但不跨越 / 和 .（见 MatchOrigin），如：
+//
+//	WithOrigins("https://*.example.com", "http://localhost:*", "file://*")
+func WithOrigins(patterns ...string) HandleOption {
+	return func(o *handleOptions) {
+		o.origins = append(o.origins, patterns...)
+	}
+}
+
+func newHandleOptions(opts []HandleOption) *handleOptions {
+	o := &handleOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,80 @@
+package ipc
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 可提供来源的 Transport，用于 WithOrigins 限制调用方
+type OriginTransport interface {
+	Transport
+	// 对端的来源，格式为 scheme://host[:port]
+	Origin() string
+}
+
+// 获取 URL 的 origin，格式为 scheme://host[:port]，解析失败时返回空字符串
+func ParseOrigin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// 判断 origin 是否匹配 pattern，pattern 中的 * 匹配任意字符（包括空），但不跨越 / 和 .
+//
+// 如 https://*.example.com 匹配 https://a.example.com，不匹配 https://a.b.example.com、https://evil.com/x.example.com
+func MatchOrigin(pattern, origin string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == '*' {
+			pattern = pattern[1:]
+			for i := 0; ; i++ {
+				if MatchOrigin(pattern, origin[i:]) {
+					return true
+				}
+				if i == len(origin) || origin[i] == '/' || origin[i] == '.' {
+					return false
+				}
+			}
+		}
+
+		if len(origin) == 0 || pattern[0] != origin[0] {
+			return false
+		}
+		pattern, origin = pattern[1:], origin[1:]
+	}
+
+	return len(origin) == 0
+}
+
+// 检查对端是否允许调用该通道
+func (r *Router) checkOrigin(call *Call) error {
+	if call.Transport == nil {
+		return nil // GO 直接调用
+	}
+
+	r.mu.RLock()
+	patterns := r.origins[call.Channel]
+	r.mu.RUnlock()
+
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	origin := ""
+	if t, ok := call.Transport.(OriginTransport); ok {
+		origin = t.Origin()
+	}
+
+	for _, pattern := range patterns {
+		if MatchOrigin(pattern, origin) {
+			return nil
+		}
+	}
+
+	msg := fmt.Sprintf("ipc channel %s 不允许来源 %q 调用", call.Channel, origin)
+	log.Warning(msg)
+	return &Error{Code: ERR_FORBIDDEN, Message: msg}
+}
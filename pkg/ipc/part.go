@@ -0,0 +1,196 @@
+package ipc
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/epkgs/blink/internal/log"
+	"github.com/epkgs/blink/pkg/utils"
+)
+
+// 默认的单条消息最大长度（字节），超过后分片发送
+const DefaultMaxMessageSize = 256 * 1024
+
+// 分片重组后的消息最大长度（字节），超过时丢弃
+const MaxPartedMessageSize = 64 * 1024 * 1024
+
+// 未收齐的分片最长保留时间
+const partExpire = time.Minute
+
+// 消息分片，Message.Type 为 TYPE_PART 时有效，Message.ID 为分片所属的消息
+type Part struct {
+	Index int    `json:"index"` // 从 0 开始
+	Total int    `json:"total"`
+	Data  string `json:"data"` // 原消息 JSON 的一段
+}
+
+type partBuffer struct {
+	chunks   []string
+	received int
+	size     int // 已收到的字节数
+	updated  time.Time
+}
+
+// 正在接收的分片
+type partBuffers struct {
+	mu      sync.Mutex
+	buffers map[peerKey]*partBuffer
+}
+
+func newPartBuffers() *partBuffers {
+	return &partBuffers{
+		buffers: make(map[peerKey]*partBuffer),
+	}
+}
+
+// 添加分片，收齐后返回完整的消息
+//
+// Total 由对端给出，须在 [1, maxTotal] 之内，避免按对端给出的数量分配内存
+func (p *partBuffers) Add(t Transport, id string, part *Part, maxTotal int) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	// 清理长时间未收齐的分片
+	for key, buf := range p.buffers {
+		if now.Sub(buf.updated) > partExpire {
+			log.Warning("IPC 消息分片 %s 未收齐，已丢弃", key.id)
+			delete(p.buffers, key)
+		}
+	}
+
+	key := peerKey{t, id}
+	buf, exist := p.buffers[key]
+	if !exist {
+		if part.Total < 1 || part.Total > maxTotal {
+			log.Error("IPC 消息分片 %s 数量无效: %d，最多 %d", id, part.Total, maxTotal)
+			return "", false
+		}
+		buf = &partBuffer{chunks: make([]string, part.Total)}
+	}
+
+	if part.Index < 0 || part.Index >= len(buf.chunks) || part.Total != len(buf.chunks) {
+		log.Error("IPC 消息分片 %s 无效: %d/%d", id, part.Index, part.Total)
+		return "", false
+	}
+	p.buffers[key] = buf
+
+	if buf.chunks[part.Index] == "" {
+		buf.received++
+	}
+	buf.size += len(part.Data) - len(buf.chunks[part.Index])
+	buf.chunks[part.Index] = part.Data
+	buf.updated = now
+
+	if buf.size > MaxPartedMessageSize {
+		log.Error("IPC 消息分片 %s 超过最大长度 %d，已丢弃", id, MaxPartedMessageSize)
+		delete(p.buffers, key)
+		return "", false
+	}
+
+	if buf.received < len(buf.chunks) {
+		return "", false
+	}
+
+	delete(p.buffers, key)
+	return strings.Join(buf.chunks, ""), true
+}
+
+// 丢弃对端未收齐的分片
+func (p *partBuffers) RemoveBy(t Transport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key := range p.buffers {
+		if key.transport == t {
+			delete(p.buffers, key)
+		}
+	}
+}
+
+// 设置单条消息的最大长度（字节），超过后分片发送，n <= 0 时不分片
+func (r *Router) SetMaxMessageSize(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxMessageSize = n
+}
+
+func (r *Router) MaxMessageSize() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.maxMessageSize
+}
+
+// 发送消息，超过最大长度时分片
+func (r *Router) sendData(t Transport, data string) error {
+	max := r.MaxMessageSize()
+	if max <= 0 || len(data) <= max {
+		return t.Send(data)
+	}
+
+	id := utils.RandString(8)
+	chunks := splitString(data, max)
+
+	for i, chunk := range chunks {
+		part, err := json.Marshal(Message{
+			ID:   id,
+			Type: TYPE_PART,
+			Part: &Part{Index: i, Total: len(chunks), Data: chunk},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := t.Send(string(part)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 收到分片，收齐后按完整消息处理
+func (r *Router) receivePart(t Transport, msg *Message) {
+	if msg.Part == nil {
+		return
+	}
+
+	// 对端按同样的最大长度分片，不分片时不应收到分片
+	max := r.MaxMessageSize()
+	if max <= 0 {
+		log.Error("IPC 未开启分片，忽略消息分片 %s", msg.ID)
+		return
+	}
+
+	if data, ok := r.parts.Add(t, msg.ID, msg.Part, maxParts(max)); ok {
+		r.Receive(t, data)
+	}
+}
+
+// 重组后不超过 MaxPartedMessageSize 时，最多的分片数量
+func maxParts(maxMessageSize int) int {
+	return (MaxPartedMessageSize + maxMessageSize - 1) / maxMessageSize
+}
+
+// 按最大长度切分，不会切断 UTF-8 字符
+func splitString(s string, max int) []string {
+	chunks := make([]string, 0, len(s)/max+1)
+
+	for len(s) > max {
+		end := max
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		if end == 0 {
+			end = max
+		}
+		chunks = append(chunks, s[:end])
+		s = s[end:]
+	}
+
+	return append(chunks, s)
+}
@@ -0,0 +1,51 @@
+package ipc
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPartRejectsInvalidTotal(t *testing.T) {
+	r := NewRouter()
+	ct, st := NewMemoryTransport()
+	defer ct.Close()
+
+	// 对端给出的分片数量不可信，不能据此分配内存
+	for _, data := range []string{
+		`{"type":"part","id":"x","part":{"index":0,"total":1e15,"data":"a"}}`,
+		`{"type":"part","id":"x","part":{"index":0,"total":1000000000000000,"data":"a"}}`,
+		`{"type":"part","id":"x","part":{"index":0,"total":0,"data":"a"}}`,
+		`{"type":"part","id":"x","part":{"index":0,"total":-1,"data":"a"}}`,
+		`{"type":"part","id":"x","part":{"index":5,"total":2,"data":"a"}}`,
+	} {
+		r.Receive(st, data)
+	}
+
+	if n := len(r.parts.buffers); n != 0 {
+		t.Fatalf("保留了 %d 个无效的分片缓存", n)
+	}
+
+	if max := maxParts(DefaultMaxMessageSize); max != MaxPartedMessageSize/DefaultMaxMessageSize {
+		t.Fatalf("maxParts = %d", max)
+	}
+}
+
+func TestPartRoundTrip(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+	client.SetMaxMessageSize(64)
+	server.SetMaxMessageSize(64)
+
+	server.Handle("echo", func(s string) string {
+		return s
+	})
+
+	long := strings.Repeat("中文abc", 100)
+	result, err := client.Call(context.Background(), ct, "echo", long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != long {
+		t.Fatalf("分片重组后的结果不一致: %d 字节", len(result.(string)))
+	}
+}
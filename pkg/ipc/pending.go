@@ -0,0 +1,246 @@
+package ipc
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 等待回复的调用的统计
+type PendingStats struct {
+	InFlight  int    // 正在等待回复的调用数
+	Completed uint64 // 已收到回复的调用数
+	Timeouts  uint64 // 超时的调用数
+	Canceled  uint64 // 被取消（调用方 ctx 结束、对端断开）的调用数
+}
+
+type pendingEntry struct {
+	id        string
+	channel   string
+	transport Transport
+	cb        resultCallback
+	deadline  time.Time // 为零值时不超时
+	index     int       // 在堆中的位置，-1 表示不在堆中
+}
+
+// 按 deadline 排序的最小堆
+type pendingHeap []*pendingEntry
+
+func (h pendingHeap) Len() int           { return len(h) }
+func (h pendingHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+
+func (h pendingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *pendingHeap) Push(x interface{}) {
+	e := x.(*pendingEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *pendingHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// 等待对端回复的调用表
+//
+// 所有超时由一个 goroutine 按最小堆统一处理；每个 callback 都在锁内被取出后才调用，保证只触发一次
+type pending struct {
+	mu      sync.Mutex
+	entries map[string]*pendingEntry
+	heap    pendingHeap
+
+	wake chan struct{} // 堆顶变化时唤醒超时处理
+	once sync.Once
+
+	completed uint64
+	timeouts  uint64
+	canceled  uint64
+}
+
+func newPending() *pending {
+	return &pending{
+		entries: make(map[string]*pendingEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// 添加等待回复的调用，timeout <= 0 时不超时
+//
+// id 已存在时不添加并返回 false，调用方应换一个 id
+func (p *pending) Add(id, channel string, t Transport, timeout time.Duration, cb resultCallback) bool {
+	p.once.Do(func() {
+		go p.loop()
+	})
+
+	e := &pendingEntry{
+		id:        id,
+		channel:   channel,
+		transport: t,
+		cb:        cb,
+		index:     -1,
+	}
+
+	p.mu.Lock()
+	if _, exist := p.entries[id]; exist {
+		p.mu.Unlock()
+		return false
+	}
+	p.entries[id] = e
+	if timeout > 0 {
+		e.deadline = time.Now().Add(timeout)
+		heap.Push(&p.heap, e)
+	}
+	first := e.index == 0
+	p.mu.Unlock()
+
+	// 新的调用最先到期，需要重新计算等待时间
+	if first {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
+
+// 收到回复，返回 false 表示该调用已结束（超时、被取消或重复回复）
+func (p *pending) Resolve(id string, result interface{}, err error) bool {
+	p.mu.Lock()
+	e := p.take(id)
+	if e != nil {
+		p.completed++
+	}
+	p.mu.Unlock()
+
+	if e == nil {
+		return false
+	}
+
+	e.cb(result, err)
+	return true
+}
+
+// 取消等待，callback 收到 err，返回 false 表示该调用已结束
+func (p *pending) Cancel(id string, err error) bool {
+	p.mu.Lock()
+	e := p.take(id)
+	if e != nil {
+		// 调用方 ctx 的超时同样计为超时
+		if errors.Is(err, context.DeadlineExceeded) {
+			p.timeouts++
+		} else {
+			p.canceled++
+		}
+	}
+	p.mu.Unlock()
+
+	if e == nil {
+		return false
+	}
+
+	e.cb(nil, err)
+	return true
+}
+
+// 取消发送到 t 的所有调用
+func (p *pending) CancelBy(t Transport, err error) {
+	var canceled []*pendingEntry
+
+	p.mu.Lock()
+	for id, e := range p.entries {
+		if e.transport == t {
+			canceled = append(canceled, p.take(id))
+		}
+	}
+	p.canceled += uint64(len(canceled))
+	p.mu.Unlock()
+
+	for _, e := range canceled {
+		e.cb(nil, err)
+	}
+}
+
+func (p *pending) Stats() PendingStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PendingStats{
+		InFlight:  len(p.entries),
+		Completed: p.completed,
+		Timeouts:  p.timeouts,
+		Canceled:  p.canceled,
+	}
+}
+
+// 从表中取出，须持有锁
+func (p *pending) take(id string) *pendingEntry {
+	e, exist := p.entries[id]
+	if !exist {
+		return nil
+	}
+
+	delete(p.entries, id)
+	if e.index >= 0 {
+		heap.Remove(&p.heap, e.index)
+	}
+
+	return e
+}
+
+// 处理到期的调用
+func (p *pending) loop() {
+	timer := time.NewTimer(time.Hour)
+	if !timer.Stop() {
+		<-timer.C
+	}
+
+	for {
+		var expired []*pendingEntry
+		wait := time.Duration(-1) // 小于 0 表示没有需要超时的调用
+
+		p.mu.Lock()
+		now := time.Now()
+		for p.heap.Len() > 0 {
+			e := p.heap[0]
+			if e.deadline.After(now) {
+				wait = e.deadline.Sub(now)
+				break
+			}
+			expired = append(expired, p.take(e.id))
+		}
+		p.timeouts += uint64(len(expired))
+		p.mu.Unlock()
+
+		for _, e := range expired {
+			e.cb(nil, &Error{Code: ERR_TIMEOUT, Message: fmt.Sprintf("ipc channel %s 等待对端回复超时", e.channel)})
+		}
+
+		if wait < 0 {
+			<-p.wake
+			continue
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-p.wake:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+	}
+}
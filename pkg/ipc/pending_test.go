@@ -0,0 +1,200 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录 callback 被调用的次数及最后一次的结果
+type settleRecorder struct {
+	mu     sync.Mutex
+	calls  int
+	result interface{}
+	err    error
+	done   chan struct{}
+}
+
+func newSettleRecorder() *settleRecorder {
+	return &settleRecorder{done: make(chan struct{}, 16)}
+}
+
+func (s *settleRecorder) cb(result interface{}, err error) {
+	s.mu.Lock()
+	s.calls++
+	s.result, s.err = result, err
+	s.mu.Unlock()
+	s.done <- struct{}{}
+}
+
+func (s *settleRecorder) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback 未被调用")
+	}
+}
+
+func (s *settleRecorder) get() (int, interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls, s.result, s.err
+}
+
+func TestPendingResolveOnce(t *testing.T) {
+	p := newPending()
+	rec := newSettleRecorder()
+
+	if !p.Add("a", "ch", nil, time.Second, rec.cb) {
+		t.Fatal("Add 失败")
+	}
+
+	if !p.Resolve("a", 1, nil) {
+		t.Fatal("第一次回复应成功")
+	}
+	if p.Resolve("a", 2, nil) {
+		t.Fatal("重复回复应被忽略")
+	}
+	if p.Cancel("a", context.Canceled) {
+		t.Fatal("已回复的调用不应再被取消")
+	}
+
+	rec.wait(t)
+	if calls, result, err := rec.get(); calls != 1 || result != 1 || err != nil {
+		t.Fatalf("calls=%d result=%v err=%v", calls, result, err)
+	}
+
+	stats := p.Stats()
+	if stats != (PendingStats{Completed: 1}) {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestPendingTimeoutThenLateReply(t *testing.T) {
+	p := newPending()
+	rec := newSettleRecorder()
+
+	p.Add("a", "ch", nil, 30*time.Millisecond, rec.cb)
+
+	rec.wait(t)
+	_, _, err := rec.get()
+	var e *Error
+	if !errors.As(err, &e) || e.Code != ERR_TIMEOUT {
+		t.Fatalf("err = %v, want %s", err, ERR_TIMEOUT)
+	}
+
+	if p.Resolve("a", 1, nil) {
+		t.Fatal("超时后的回复应被忽略")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if calls, _, _ := rec.get(); calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+
+	stats := p.Stats()
+	if stats != (PendingStats{Timeouts: 1}) {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestPendingTimeoutOrder(t *testing.T) {
+	p := newPending()
+
+	order := make(chan string, 3)
+	add := func(id string, d time.Duration) {
+		p.Add(id, "ch", nil, d, func(interface{}, error) {
+			order <- id
+		})
+	}
+
+	// 后添加、先到期的调用须唤醒超时处理
+	add("slow", 200*time.Millisecond)
+	add("fast", 20*time.Millisecond)
+	add("never", 0)
+
+	for _, want := range []string{"fast", "slow"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("到期顺序 %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s 未超时", want)
+		}
+	}
+
+	stats := p.Stats()
+	if stats.InFlight != 1 || stats.Timeouts != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	if !p.Cancel("never", context.Canceled) {
+		t.Fatal("不超时的调用应可取消")
+	}
+}
+
+func TestPendingDuplicateID(t *testing.T) {
+	p := newPending()
+	first, second := newSettleRecorder(), newSettleRecorder()
+
+	if !p.Add("a", "ch", nil, 20*time.Millisecond, first.cb) {
+		t.Fatal("Add 失败")
+	}
+	if p.Add("a", "ch", nil, time.Second, second.cb) {
+		t.Fatal("重复的 id 应被拒绝")
+	}
+
+	first.wait(t)
+	time.Sleep(20 * time.Millisecond)
+
+	if calls, _, _ := second.get(); calls != 0 {
+		t.Fatalf("被拒绝的 callback 被调用了 %d 次", calls)
+	}
+	if stats := p.Stats(); stats != (PendingStats{Timeouts: 1}) {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestPendingCancelCounters(t *testing.T) {
+	p := newPending()
+	ta, tb := NewMemoryTransport()
+	defer ta.Close()
+
+	recs := map[string]*settleRecorder{}
+	for _, id := range []string{"a1", "a2", "b1", "c1", "d1"} {
+		recs[id] = newSettleRecorder()
+	}
+
+	p.Add("a1", "ch", ta, time.Second, recs["a1"].cb)
+	p.Add("a2", "ch", ta, 0, recs["a2"].cb)
+	p.Add("b1", "ch", tb, time.Second, recs["b1"].cb)
+	p.Add("c1", "ch", tb, time.Second, recs["c1"].cb)
+	p.Add("d1", "ch", tb, time.Second, recs["d1"].cb)
+
+	closed := &Error{Code: ERR_CANCELED}
+	p.CancelBy(ta, closed)
+	recs["a1"].wait(t)
+	recs["a2"].wait(t)
+	if _, _, err := recs["a1"].get(); err != closed {
+		t.Fatalf("a1 err = %v", err)
+	}
+
+	// 调用方 ctx 超时计为超时，其他取消计为取消
+	p.Cancel("b1", context.DeadlineExceeded)
+	p.Cancel("c1", context.Canceled)
+	p.Resolve("d1", nil, nil)
+
+	stats := p.Stats()
+	want := PendingStats{InFlight: 0, Completed: 1, Timeouts: 1, Canceled: 3}
+	if stats != want {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+
+	if calls, _, _ := recs["b1"].get(); calls != 1 {
+		t.Fatalf("b1 calls = %d", calls)
+	}
+}
@@ -0,0 +1,205 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 消息方向
+const (
+	DIRECTION_IN  = "in"  // 对端 -> GO
+	DIRECTION_OUT = "out" // GO -> 对端
+)
+
+// 可选实现，在记录中标识对端（如 View 的句柄），未实现时使用 Transport 的地址
+type NamedTransport interface {
+	Transport
+	Name() string
+}
+
+// 一条消息记录，Recorder 以 JSONL 格式逐行写入
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"direction"` // 见 DIRECTION_*
+	Peer      string          `json:"peer"`      // 对端标识
+	Message   json.RawMessage `json:"message"`   // 原始消息（分片已合并）
+}
+
+// 过滤记录，返回 false 时不记录
+type RecordFilter func(rec *Record, msg *Message) bool
+
+// 将 Router 收发的消息记录到 JSONL，可由 Replay 回放
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	filter RecordFilter
+}
+
+// 创建记录器，filter 为 nil 时记录所有消息
+func NewRecorder(w io.Writer, filter RecordFilter) *Recorder {
+	return &Recorder{w: w, filter: filter}
+}
+
+// 记录一条消息
+func (rec *Recorder) Record(direction string, t Transport, data string) error {
+	r := Record{
+		Time:      time.Now(),
+		Direction: direction,
+		Peer:      peerName(t),
+		Message:   json.RawMessage(data),
+	}
+
+	if rec.filter != nil {
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return err
+		}
+		if !rec.filter(&r, &msg) {
+			return nil
+		}
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	_, err = rec.w.Write(append(line, '\n'))
+	return err
+}
+
+func peerName(t Transport) string {
+	if nt, ok := t.(NamedTransport); ok {
+		return nt.Name()
+	}
+	return fmt.Sprintf("%p", t)
+}
+
+// 设置记录器，为 nil 时停止记录
+func (r *Router) SetRecorder(rec *Recorder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorder = rec
+}
+
+func (r *Router) record(direction string, t Transport, data string) {
+	r.mu.RLock()
+	rec := r.recorder
+	r.mu.RUnlock()
+
+	if rec == nil {
+		return
+	}
+
+	if err := rec.Record(direction, t, data); err != nil {
+		log.Error("IPC 消息记录出错: %s", err.Error())
+	}
+}
+
+// 回放的一次调用
+type ReplayResult struct {
+	Record   Record      // 对端发起调用的记录
+	Message  Message     // 调用消息
+	Result   interface{} // 本次回放的结果
+	Err      error       // 本次回放的错误
+	Expected *Message    // 记录中的回复，没有时为 nil
+}
+
+// 回放记录中对端发起的普通调用（invoke/sent），不需要浏览器
+//
+// 按记录的顺序，经由内存传输逐个调用 router 里注册的 handler（同样经过中间件），
+// 返回每次调用的结果，以及记录中对应的回复，用于比对。流式调用、事件等其他消息将被忽略
+func Replay(ctx context.Context, router *Router, rd io.Reader) ([]ReplayResult, error) {
+
+	// 不同对端的调用 ID 可能相同，回复须按对端区分
+	type replyKey struct {
+		peer string
+		id   string
+	}
+
+	var records []Record
+	replies := map[replyKey]*Message{} // 记录中的回复
+
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("第 %d 行记录解析出错: %w", line, err)
+		}
+
+		var msg Message
+		if err := json.Unmarshal(rec.Message, &msg); err != nil {
+			return nil, fmt.Errorf("第 %d 行消息解析出错: %w", line, err)
+		}
+
+		if rec.Direction == DIRECTION_OUT && msg.ReplyId != "" && msg.Type == "" {
+			replies[replyKey{rec.Peer, msg.ReplyId}] = &msg
+		}
+
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// 模拟对端
+	peer, t := NewMemoryTransport()
+	defer peer.Close()
+
+	client := NewRouter()
+	client.SetTimeout(router.Timeout())
+	client.Attach(peer)
+	defer client.Detach(peer)
+
+	router.Attach(t)
+	defer router.Detach(t)
+
+	var results []ReplayResult
+
+	for _, rec := range records {
+		if rec.Direction != DIRECTION_IN {
+			continue
+		}
+
+		var msg Message
+		_ = json.Unmarshal(rec.Message, &msg)
+		if msg.Channel == "" || msg.ReplyId != "" || msg.Type != "" {
+			continue
+		}
+
+		for i, arg := range msg.Args {
+			msg.Args[i] = decodeBinary(arg)
+		}
+
+		result := ReplayResult{Record: rec, Message: msg, Expected: replies[replyKey{rec.Peer, msg.ID}]}
+
+		if msg.ID == "" {
+			result.Err = client.Notify(peer, msg.Channel, msg.Args...)
+		} else {
+			result.Result, result.Err = client.Call(ctx, peer, msg.Channel, msg.Args...)
+		}
+
+		results = append(results, result)
+
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,93 @@
+package ipc
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 带名称的传输，用于在记录中区分对端
+type namedTransport struct {
+	*MemoryTransport
+	name string
+}
+
+func (t namedTransport) Name() string {
+	return t.name
+}
+
+// 线程安全的缓冲，Recorder 与测试同时访问
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// 记录两个对端以相同 ID 发起的调用，回放时各自对应记录中的回复
+func TestRecordReplay(t *testing.T) {
+	server := NewRouter()
+	server.Handle("add", func(a, b int) int {
+		return a + b
+	})
+
+	var buf syncBuffer
+	server.SetRecorder(NewRecorder(&buf, nil))
+
+	for i, peer := range []struct {
+		name string
+		msg  string
+	}{
+		{"a", `{"id":"same","channel":"add","args":[1,2]}`},
+		{"b", `{"id":"same","channel":"add","args":[10,20]}`},
+	} {
+		ct, st := NewMemoryTransport()
+		defer ct.Close()
+		server.Attach(namedTransport{st, peer.name})
+		if err := ct.Send(peer.msg); err != nil {
+			t.Fatal(err)
+		}
+
+		// 调用及其回复，逐个对端记录，保证顺序
+		waitFor(t, "记录", func() bool {
+			return strings.Count(buf.String(), "\n") == 2*(i+1)
+		})
+	}
+	server.SetRecorder(nil)
+
+	results, err := Replay(context.Background(), server, strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	for i, want := range []struct {
+		peer   string
+		result float64
+	}{
+		{"a", 3},
+		{"b", 30},
+	} {
+		got := results[i]
+		if got.Record.Peer != want.peer || got.Err != nil || got.Result != want.result {
+			t.Fatalf("results[%d] = %+v", i, got)
+		}
+		if got.Expected == nil || got.Expected.Result != want.result {
+			t.Fatalf("results[%d].Expected = %+v, want %v", i, got.Expected, want.result)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package ipc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type targetCtxKey struct{}
+
+// 指定调用哪个对端注册的通道
+func withTarget(ctx context.Context, t Transport) context.Context {
+	return context.WithValue(ctx, targetCtxKey{}, t)
+}
+
+func targetFromContext(ctx context.Context) (Transport, bool) {
+	t, ok := ctx.Value(targetCtxKey{}).(Transport)
+	return t, ok
+}
+
+// 对某个对端的调用结果
+type Result struct {
+	Transport Transport
+	Result    interface{}
+	Err       error
+}
+
+// 登记对端注册的通道
+//
+// 同一通道可由多个对端注册，GO 调用时：
+//   - Invoke：发送到最近注册的对端
+//   - Sent：广播到所有注册了该通道的对端
+//   - InvokeOn / SentOn：发送到指定的对端
+//   - InvokeAll：并发调用所有对端并收集结果
+//
+// 对端发起的调用只会发送到其自身，不能调用其他对端注册的通道（见 SetCrossPeerRemotes）
+//
+// 不能覆盖 GO 注册的通道，此时返回 ERR_FORBIDDEN 错误
+func (r *Router) RegisterRemote(t Transport, channel string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exist := r.remotes[channel]; !exist {
+		if _, exist := r.handlers[channel]; exist {
+			return &Error{Code: ERR_FORBIDDEN, Message: fmt.Sprintf("ipc channel %s 已由 GO 注册，不能覆盖", channel)}
+		}
+
+		r.handlers[channel] = r.remoteHandler(channel)
+		r.channels[channel] = ChannelInfo{
+			Channel: channel,
+			Source:  SOURCE_JS,
+		}
+		delete(r.origins, channel)
+	}
+
+	// 重复注册时移到最后，作为最近注册的对端
+	remotes := removeTransport(r.remotes[channel], t)
+	r.remotes[channel] = append(remotes, t)
+
+	return nil
+}
+
+// 设置是否允许对端调用其他对端注册的通道，默认不允许
+//
+// 不允许时，对端（包括 handler 以对端发起调用的 ctx 转发）只能调用自己注册的通道，否则返回 ERR_FORBIDDEN 错误；
+// 允许后，如一个页面可以调用另一个页面注册的 JS handler，请确认所有对端都是可信的
+func (r *Router) SetCrossPeerRemotes(allow bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.crossPeerRemotes = allow
+}
+
+// 移除对端注册的通道
+func (r *Router) UnregisterRemote(t Transport, channel string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unregisterRemote(t, channel)
+}
+
+// 须持有锁
+func (r *Router) unregisterRemote(t Transport, channel string) {
+	remotes, exist := r.remotes[channel]
+	if !exist {
+		return
+	}
+
+	remotes = removeTransport(remotes, t)
+	if len(remotes) > 0 {
+		r.remotes[channel] = remotes
+		return
+	}
+
+	delete(r.remotes, channel)
+	delete(r.handlers, channel)
+	delete(r.channels, channel)
+}
+
+// 对端已失效（如页面重新加载）：移除其注册的通道和订阅的事件，取消其发起的流，以及正在等待其回复的调用
+//
+// 对端仍保持接入，可重新注册通道
+func (r *Router) Reset(t Transport) {
+	r.mu.Lock()
+	for channel := range r.remotes {
+		r.unregisterRemote(t, channel)
+	}
+	r.mu.Unlock()
+
+	r.events.removePeer(t)
+	r.parts.RemoveBy(t)
+	r.streams.CancelBy(t)
+	r.calls.CancelBy(t)
+	r.pending.CancelBy(t, &Error{Code: ERR_CANCELED, Message: "对端已断开"})
+}
+
+// 注册了该通道的对端，按注册顺序
+func (r *Router) Remotes(channel string) []Transport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]Transport(nil), r.remotes[channel]...)
+}
+
+// 调用指定对端注册的通道，同样经过中间件
+func (r *Router) InvokeOn(ctx context.Context, t Transport, channel string, args ...interface{}) (interface{}, error) {
+	return r.InvokeContext(withTarget(ctx, t), channel, args...)
+}
+
+// 调用指定对端注册的通道，不等待返回值
+func (r *Router) SentOn(t Transport, channel string, args ...interface{}) error {
+	return r.sent(withTarget(context.Background(), t), channel, args...)
+}
+
+// 并发调用所有注册了该通道的对端，按注册顺序返回结果
+func (r *Router) InvokeAll(ctx context.Context, channel string, args ...interface{}) ([]Result, error) {
+	remotes := r.Remotes(channel)
+	if len(remotes) == 0 {
+		return nil, &Error{Code: ERR_NOT_FOUND, Message: fmt.Sprintf("ipc channel %s 没有对端注册", channel)}
+	}
+
+	results := make([]Result, len(remotes))
+
+	var wg sync.WaitGroup
+	for i, t := range remotes {
+		wg.Add(1)
+		go func(i int, t Transport) {
+			defer wg.Done()
+			result, err := r.InvokeOn(ctx, t, channel, args...)
+			results[i] = Result{Transport: t, Result: result, Err: err}
+		}(i, t)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// 将对端注册的通道转为 handler
+func (r *Router) remoteHandler(channel string) handler {
+	return func(ctx context.Context, cb resultCallback, args ...interface{}) {
+
+		r.mu.RLock()
+		targets := append([]Transport(nil), r.remotes[channel]...)
+		crossPeer := r.crossPeerRemotes
+		r.mu.RUnlock()
+
+		// 对端发起的调用，默认只能调用自己注册的通道
+		if from, ok := TransportFromContext(ctx); ok && !crossPeer {
+			if !containsTransport(targets, from) {
+				if cb != nil {
+					cb(nil, &Error{Code: ERR_FORBIDDEN, Message: fmt.Sprintf("ipc channel %s 由其他对端注册，不允许调用", channel)})
+				}
+				return
+			}
+			targets = []Transport{from}
+		}
+
+		if t, ok := targetFromContext(ctx); ok {
+			if !containsTransport(targets, t) {
+				if cb != nil {
+					cb(nil, &Error{Code: ERR_NOT_FOUND, Message: fmt.Sprintf("ipc channel %s 未由该对端注册", channel)})
+				}
+				return
+			}
+			targets = []Transport{t}
+		}
+
+		if len(targets) == 0 {
+			if cb != nil {
+				cb(nil, &Error{Code: ERR_NOT_FOUND, Message: fmt.Sprintf("ipc channel %s not exist", channel)})
+			}
+			return
+		}
+
+		// 无须回复时广播
+		if cb == nil {
+			for _, t := range targets {
+				_ = r.Notify(t, channel, args...)
+			}
+			return
+		}
+
+		r.request(ctx, targets[len(targets)-1], channel, args, cb)
+	}
+}
+
+func removeTransport(list []Transport, t Transport) []Transport {
+	result := make([]Transport, 0, len(list))
+	for _, item := range list {
+		if item != t {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func containsTransport(list []Transport, t Transport) bool {
+	for _, item := range list {
+		if item == t {
+			return true
+		}
+	}
+	return false
+}
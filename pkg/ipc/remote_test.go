@@ -0,0 +1,158 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// 一个 hub 接入多个对端，每个对端都注册了 who 通道，返回自己的名称
+func newHub(t *testing.T, names ...string) (hub *Router, hts []*MemoryTransport, peers []*Router, pts []*MemoryTransport) {
+	t.Helper()
+
+	hub = NewRouter()
+	for _, name := range names {
+		name := name
+		ht, pt := NewMemoryTransport()
+		peer := NewRouter()
+		hub.Attach(ht)
+		peer.Attach(pt)
+		t.Cleanup(func() {
+			ht.Close()
+		})
+
+		peer.Handle("who", func() (string, error) {
+			if name == "bad" {
+				return "", errors.New("bad peer")
+			}
+			return name, nil
+		})
+
+		hts, peers, pts = append(hts, ht), append(peers, peer), append(pts, pt)
+	}
+
+	return hub, hts, peers, pts
+}
+
+func TestRemoteInvokeMostRecent(t *testing.T) {
+	hub, hts, _, _ := newHub(t, "a", "b")
+
+	for _, tt := range []struct {
+		register *MemoryTransport
+		want     string
+	}{
+		{hts[0], "a"},
+		{hts[1], "b"},
+		{hts[0], "a"}, // 重复注册后成为最近注册的对端
+	} {
+		if err := hub.RegisterRemote(tt.register, "who"); err != nil {
+			t.Fatal(err)
+		}
+		result, err := hub.Invoke("who")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != tt.want {
+			t.Fatalf("result = %#v, want %q", result, tt.want)
+		}
+	}
+
+	// 最近注册的对端移除后，调用之前注册的对端
+	hub.UnregisterRemote(hts[0], "who")
+	if result, err := hub.Invoke("who"); err != nil || result != "b" {
+		t.Fatalf("result = %#v, %v, want b", result, err)
+	}
+}
+
+func TestRemoteInvokeOn(t *testing.T) {
+	hub, hts, _, _ := newHub(t, "a", "b", "c")
+	hub.RegisterRemote(hts[0], "who")
+	hub.RegisterRemote(hts[1], "who")
+
+	for i, want := range []string{"a", "b"} {
+		result, err := hub.InvokeOn(context.Background(), hts[i], "who")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != want {
+			t.Fatalf("result = %#v, want %q", result, want)
+		}
+	}
+
+	// 未注册该通道的对端
+	_, err := hub.InvokeOn(context.Background(), hts[2], "who")
+	if e := asError(t, err); e.Code != ERR_NOT_FOUND {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_NOT_FOUND)
+	}
+}
+
+func TestRemoteInvokeAll(t *testing.T) {
+	hub, hts, _, _ := newHub(t, "a", "bad", "c")
+
+	if _, err := hub.InvokeAll(context.Background(), "who"); asError(t, err).Code != ERR_NOT_FOUND {
+		t.Fatalf("没有对端注册时应返回 ERR_NOT_FOUND: %v", err)
+	}
+
+	for _, i := range []int{2, 0, 1} {
+		hub.RegisterRemote(hts[i], "who")
+	}
+
+	results, err := hub.InvokeAll(context.Background(), "who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	// 按注册顺序返回，单个对端出错不影响其他对端
+	for i, want := range []struct {
+		transport Transport
+		result    interface{}
+		err       string
+	}{
+		{hts[2], "c", ""},
+		{hts[0], "a", ""},
+		{hts[1], nil, "bad peer"},
+	} {
+		got := results[i]
+		if got.Transport != want.transport || got.Result != want.result {
+			t.Fatalf("results[%d] = %+v", i, got)
+		}
+		if want.err == "" && got.Err != nil || want.err != "" && (got.Err == nil || got.Err.Error() != want.err) {
+			t.Fatalf("results[%d].Err = %v, want %q", i, got.Err, want.err)
+		}
+	}
+}
+
+// 对端默认只能调用自己注册的通道
+func TestRemoteCrossPeer(t *testing.T) {
+	hub, hts, peers, pts := newHub(t, "a", "b")
+	hub.RegisterRemote(hts[0], "who")
+
+	_, err := peers[1].Call(context.Background(), pts[1], "who")
+	if e := asError(t, err); e.Code != ERR_FORBIDDEN {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_FORBIDDEN)
+	}
+
+	// 注册了该通道的对端调用时，发送到其自身，而不是最近注册的对端
+	hub.RegisterRemote(hts[1], "who")
+	result, err := peers[0].Call(context.Background(), pts[0], "who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "a" {
+		t.Fatalf("result = %#v, want a", result)
+	}
+
+	// 允许跨对端调用后，按最近注册的对端调用
+	hub.UnregisterRemote(hts[1], "who")
+	hub.SetCrossPeerRemotes(true)
+	result, err = peers[1].Call(context.Background(), pts[1], "who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "a" {
+		t.Fatalf("result = %#v, want a", result)
+	}
+}
@@ -0,0 +1,654 @@
+// Package ipc 实现 GO 与对端（页面里的 JS）之间的 IPC 协议：消息路由、等待回复、handler 调用。
+//
+// 本包不依赖 miniblink 和操作系统，消息的收发由 Transport 完成，
+// blink 使用基于 View 的 Transport，测试时可使用 NewMemoryTransport 创建的内存传输。
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/epkgs/blink/internal/cast"
+	"github.com/epkgs/blink/internal/log"
+	"github.com/epkgs/blink/pkg/utils"
+)
+
+// 默认的调用超时时间
+const DefaultTimeout = 10 * time.Second
+
+type resultCallback func(result interface{}, err error)
+
+// resultCallback 用于区分无须返回值的情况
+//
+// ctx 被取消时，handler 应尽快结束，并放弃回复
+type handler func(ctx context.Context, cb resultCallback, args ...interface{})
+
+// 消息路由
+//
+// 管理 GO 注册的 handler、对端注册的通道，以及等待对端回复的调用
+type Router struct {
+	mu       sync.RWMutex
+	handlers map[string]handler
+	channels map[string]ChannelInfo // 通道描述，与 handlers 一一对应
+	remotes  map[string][]Transport // 对端注册的通道 -> 注册了该通道的对端，按注册顺序
+	origins  map[string][]string    // 限制了调用来源的 GO 通道
+	services map[string]ServiceInfo // 通过 Expose 暴露的 GO 对象
+
+	middlewares []Middleware
+
+	pending *pending
+	streams *streams
+	calls   *invocations // 对端发起、正在执行的调用
+	events  *eventBus
+	parts   *partBuffers
+
+	maxMessageSize int // 单条消息的最大长度，超过后分片发送
+
+	recorder *Recorder // 消息记录器，见 SetRecorder
+
+	lenientArgs bool // 宽松地转换参数，见 SetStrictArgs
+
+	crossPeerRemotes bool // 允许对端调用其他对端注册的通道，见 SetCrossPeerRemotes
+
+	timeout  time.Duration            // 默认的超时时间
+	timeouts map[string]time.Duration // 单独设置了超时时间的通道
+}
+
+func NewRouter() *Router {
+	r := &Router{
+		handlers: make(map[string]handler),
+		channels: make(map[string]ChannelInfo),
+		remotes:  make(map[string][]Transport),
+		origins:  make(map[string][]string),
+		services: make(map[string]ServiceInfo),
+		pending:  newPending(),
+		streams:  newStreams(),
+		calls:    newInvocations(),
+		events:   newEventBus(),
+		parts:    newPartBuffers(),
+
+		maxMessageSize: DefaultMaxMessageSize,
+		timeout:        DefaultTimeout,
+		timeouts:       make(map[string]time.Duration),
+	}
+
+	r.registerChannelsHandler()
+
+	return r
+}
+
+// 接入一个对端，之后由该对端发来的消息都交给 Router 处理
+func (r *Router) Attach(t Transport) {
+	t.OnReceive(func(data string) {
+		r.Receive(t, data)
+	})
+}
+
+// 断开一个对端，同时 Reset
+func (r *Router) Detach(t Transport) {
+	t.OnReceive(nil)
+	r.Reset(t)
+}
+
+// 设置默认的超时时间，d <= 0 时不超时
+func (r *Router) SetTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeout = d
+}
+
+// 默认的超时时间
+func (r *Router) Timeout() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.timeout
+}
+
+// 单独设置通道的超时时间，d <= 0 时不超时
+func (r *Router) SetChannelTimeout(channel string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeouts[channel] = normalizeTimeout(d)
+}
+
+// 不超时统一以 -1 表示，与未单独设置（0）区分
+func normalizeTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return -1
+	}
+	return d
+}
+
+// 通道的超时时间，未单独设置时为默认值
+func (r *Router) timeoutOf(channel string) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if d, exist := r.timeouts[channel]; exist {
+		return d
+	}
+	return r.timeout
+}
+
+// 按通道的超时时间设置 ctx 的 deadline
+func (r *Router) withTimeout(ctx context.Context, channel string) (context.Context, context.CancelFunc) {
+	d := r.timeoutOf(channel)
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// 等待对端回复的调用的统计
+func (r *Router) PendingStats() PendingStats {
+	return r.pending.Stats()
+}
+
+// 处理对端发来的一条消息
+//
+// 普通调用和流式调用在新的 goroutine 里执行，不会阻塞调用方
+func (r *Router) Receive(t Transport, data string) {
+	var msg Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		log.Error("IPC 消息 JSON 解析出错(%s): %s", err.Error(), data)
+		return
+	}
+
+	if msg.Type == TYPE_PART {
+		r.receivePart(t, &msg)
+		return
+	}
+
+	r.record(DIRECTION_IN, t, data)
+
+	// 还原二进制数据
+	for i, arg := range msg.Args {
+		msg.Args[i] = decodeBinary(arg)
+	}
+	msg.Result = decodeBinary(msg.Result)
+
+	switch {
+	case msg.ReplyId != "" && msg.Type == TYPE_CANCEL:
+		// 取消的可能是流式调用，也可能是普通调用
+		if !r.calls.Cancel(t, msg.ReplyId) {
+			r.handleStreamControl(t, &msg)
+		}
+	case msg.ReplyId != "" && msg.Type == TYPE_ACK:
+		r.handleStreamControl(t, &msg)
+	case msg.ReplyId != "":
+		r.handleReply(&msg)
+	case msg.Type == TYPE_SUB || msg.Type == TYPE_UNSUB || msg.Type == TYPE_EVENT:
+		// GO 的订阅回调可能耗时较长，订阅时还会向对端回放保留事件，
+		// 不能在 Receive 中处理（blink 下 Receive 运行在 JS 回调里，发送会重入 RunJS），按顺序在队列中分发
+		r.events.enqueue(func() {
+			r.handleEventMessage(t, &msg)
+		})
+	case msg.Type == TYPE_STREAM && msg.Channel != "":
+		// 流式调用持续时间较长
+		go r.streamByPeer(t, &msg)
+	case msg.Channel != "":
+		go r.invokeByPeer(t, &msg)
+	}
+}
+
+// GO 调用handler
+//
+//	一、GO 调用 GO handler，直接调用并返回
+//
+//	二、GO 调用对端 handler, 和 GO 调用 GO 流程一样，唯一区别是执行的 `handler` 是由 RegisterRemote 转化后的对端 handler，
+//	多个对端注册了同一通道时，发送到最近注册的对端
+//
+// 超过通道的超时时间（见 SetTimeout、SetChannelTimeout）未返回结果，将返回 context.DeadlineExceeded
+func (r *Router) Invoke(channel string, args ...interface{}) (interface{}, error) {
+	ctx, cancel := r.withTimeout(context.Background(), channel)
+	defer cancel()
+
+	return r.InvokeContext(ctx, channel, args...)
+}
+
+// 与 Invoke 相同，但由调用方通过 ctx 控制超时与取消
+//
+// ctx 被取消后立即返回 ctx.Err()，同时取消传递给 GO handler 的 ctx，或移除等待对端回复的 callback
+func (r *Router) InvokeContext(ctx context.Context, channel string, args ...interface{}) (interface{}, error) {
+	t, _ := TransportFromContext(ctx)
+
+	return r.dispatch(&Call{
+		Context:   ctx,
+		Channel:   channel,
+		Args:      args,
+		Transport: t,
+	})
+}
+
+// 调用通道，不等待返回值
+//
+// 对端注册的通道将广播到所有注册了该通道的对端
+func (r *Router) Sent(channel string, args ...interface{}) error {
+	return r.sent(context.Background(), channel, args...)
+}
+
+func (r *Router) sent(ctx context.Context, channel string, args ...interface{}) error {
+	t, _ := TransportFromContext(ctx)
+
+	_, err := r.dispatch(&Call{
+		Context:   ctx,
+		Channel:   channel,
+		Args:      args,
+		Transport: t,
+		Notify:    true,
+	})
+
+	return err
+}
+
+func (r *Router) HasChannel(channel string) (exist bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exist = r.handlers[channel]
+	return
+}
+
+func (r *Router) lookup(channel string) (handler, error) {
+	r.mu.RLock()
+	h, exist := r.handlers[channel]
+	r.mu.RUnlock()
+
+	if !exist {
+		msg := fmt.Sprintf("ipc channel %s not exist", channel)
+		log.Error(msg)
+		return nil, &Error{Code: ERR_NOT_FOUND, Message: msg}
+	}
+
+	return h, nil
+}
+
+// 以同步的方式等待 callback 的第一次回复
+func await(ctx context.Context, call func(ctx context.Context, cb resultCallback)) (interface{}, error) {
+
+	// 调用结束后取消 ctx，通知 handler 停止处理
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type reply struct {
+		result interface{}
+		err    error
+	}
+
+	ch := make(chan reply, 1)
+
+	// 将 callback 转 chan，仅接收第一次回复
+	call(ctx, func(res interface{}, e error) {
+		select {
+		case ch <- reply{res, e}:
+		default:
+		}
+	})
+
+	select {
+	case r := <-ch:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GO 注册 Handler
+//
+// handler 必须为函数，参数任意，返回值最多为2个
+//   - 1个返回值：会自动判断返回值是否为 error
+//   - 2个返回值：第一个为 结果，第二个为 error
+//
+// 以下类型的参数由 IPC 自动填充，不占用调用方传入的参数位置：
+//   - context.Context：调用方取消或超时后，该 ctx 会被取消。对端发起的调用，可通过 TransportFromContext 获取对端
+//   - *Stream：流式通道，通过 Write 向对端持续推送数据，JS 端使用 ipc.stream 调用
+//
+// 第一个返回值为 chan 时，同样视为流式通道，chan 里的数据将逐条推送到对端，直到 chan 关闭
+func (r *Router) Handle(channel string, fn interface{}, opts ...HandleOption) {
+
+	// 使用反射获取处理函数的类型
+	handlerVal := reflect.ValueOf(fn)
+	if handlerVal.Kind() != reflect.Func {
+		panic(fmt.Sprintf("channel %s, handler must be a function", channel))
+	}
+
+	handlerType := handlerVal.Type()
+
+	// 通道单独设置的参数转换方式
+	strict := newHandleOptions(opts).strict
+
+	// 是否为流式通道
+	streamOut := handlerType.NumOut() > 0 && handlerType.Out(0).Kind() == reflect.Chan && handlerType.Out(0).ChanDir()&reflect.RecvDir != 0
+	streamIn := false
+	for i := 0; i < handlerType.NumIn(); i++ {
+		if handlerType.In(i) == streamType {
+			streamIn = true
+		}
+	}
+
+	h := func(ctx context.Context, cb resultCallback, inputs ...interface{}) {
+
+		reply := func(result interface{}, err error) {
+			if cb != nil {
+				cb(result, err)
+			}
+		}
+
+		stream, hasStream := streamFromContext(ctx)
+		if (streamIn || streamOut) && !hasStream {
+			reply(nil, fmt.Errorf("ipc channel %s 为流式通道，请使用 ipc.stream 调用", channel))
+			return
+		}
+
+		inputSize := len(inputs)
+
+		// 构造参数列表
+		pCount := handlerType.NumIn()
+		isVariadic := handlerType.IsVariadic()
+		if isVariadic {
+			pCount = pCount - 1
+		}
+
+		// 转换参数，失败时返回 *ArgumentError
+		strictArgs := r.strictArgs(strict)
+		convert := func(param reflect.Type, index int) (reflect.Value, error) {
+			val, err := cast.ConvertParam(strictArgs, param, inputs[index])
+			if err != nil {
+				return val, &ArgumentError{
+					Channel:  channel,
+					Index:    index,
+					Expected: param.String(),
+					Actual:   cast.JSTypeOf(inputs[index]),
+					Err:      err,
+				}
+			}
+			return val, nil
+		}
+
+		idx := 0 // 当前使用到的传入参数
+		inVals := make([]reflect.Value, pCount)
+		for i := 0; i < pCount; i++ {
+
+			param := handlerType.In(i)
+
+			// 自动填充的参数
+			switch param {
+			case contextType:
+				inVals[i] = reflect.ValueOf(&ctx).Elem()
+				continue
+			case streamType:
+				inVals[i] = reflect.ValueOf(stream)
+				continue
+			}
+
+			var inputVal reflect.Value
+			var err error
+
+			if idx < inputSize {
+				inputVal, err = convert(param, idx)
+				if err != nil {
+					reply(nil, err)
+					return
+				}
+			} else {
+				inputVal = reflect.Zero(param)
+			}
+
+			idx++
+			inVals[i] = inputVal
+		}
+
+		if isVariadic && idx < inputSize {
+			// 处理可变参数
+			elem := handlerType.In(handlerType.NumIn() - 1).Elem()
+			for i := idx; i < inputSize; i++ {
+				inputVal, err := convert(elem, i)
+				if err != nil {
+					reply(nil, err)
+					log.Error(err.Error())
+					return
+				}
+				inVals = append(inVals, inputVal)
+			}
+		}
+
+		// 异步处理 handler，ctx 取消后由调用方（InvokeContext）负责返回，handler 通过 ctx 自行结束
+		go func() {
+
+			defer func() {
+				if r := recover(); r != nil {
+					err, ok := r.(error)
+					if !ok {
+						err = fmt.Errorf("%v", r)
+					}
+					log.Error("panic by ipc handler[ %v ]: %v", channel, err)
+					reply(nil, err)
+				}
+			}()
+
+			// 调用处理函数
+			out := handlerVal.Call(inVals)
+
+			if streamOut {
+				// 返回 chan 时，错误仅由第二个返回值给出
+				if len(out) == 2 {
+					if err, ok := out[1].Interface().(error); ok && err != nil {
+						reply(nil, err)
+						return
+					}
+				}
+				reply(nil, pumpStream(ctx, stream, out[0]))
+				return
+			}
+
+			if cb == nil {
+				return
+			}
+
+			cb(handlerResult(out))
+		}()
+	}
+
+	r.register(channel, h, newChannelInfo(channel, handlerType), opts)
+}
+
+func (r *Router) register(channel string, h handler, info ChannelInfo, opts []HandleOption) {
+	o := newHandleOptions(opts)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[channel] = h
+	r.channels[channel] = info
+	delete(r.remotes, channel)
+
+	if o.hasTimeout {
+		r.timeouts[channel] = normalizeTimeout(o.timeout)
+	}
+
+	if len(o.origins) > 0 {
+		r.origins[channel] = o.origins
+	} else {
+		delete(r.origins, channel)
+	}
+}
+
+// 将 handler 的返回值转为 结果 和 error
+func handlerResult(out []reflect.Value) (interface{}, error) {
+
+	switch len(out) {
+	case 0:
+		// 没有返回值
+		return nil, nil
+	case 1:
+		// 只有一个返回值
+		result := out[0].Interface()
+
+		switch res := result.(type) {
+		case error:
+			return nil, res
+		default:
+			return res, nil
+		}
+	case 2:
+		// 有2个返回值
+		res := out[0].Interface()
+		var err error
+		switch e := out[1].Interface().(type) {
+		case error:
+			err = e
+		default:
+			err = nil
+		}
+		return res, err
+	default:
+		// 多个返回值
+		return nil, fmt.Errorf("more than 2 return values are not supported")
+	}
+}
+
+// 对端调用 handler
+func (r *Router) invokeByPeer(t Transport, msg *Message) {
+
+	// 如果 ID 为空，则无须回复返回值
+	if msg.ID == "" {
+		_ = r.sent(withTransport(context.Background(), t), msg.Channel, msg.Args...)
+		return
+	}
+
+	// 对端可通过 TYPE_CANCEL 消息取消调用
+	ctx, cancelCause := context.WithCancelCause(withTransport(context.Background(), t))
+	defer cancelCause(nil)
+
+	r.calls.Add(t, msg.ID, cancelCause)
+	defer r.calls.Del(t, msg.ID)
+
+	// 对端指定了超时时间时，以对端为准，保证两端一致
+	var cancel context.CancelFunc
+	switch {
+	case msg.Timeout > 0:
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(msg.Timeout)*time.Millisecond)
+	case msg.Timeout < 0:
+		ctx, cancel = context.WithCancel(ctx)
+	default:
+		ctx, cancel = r.withTimeout(ctx, msg.Channel)
+	}
+	defer cancel()
+
+	// 调用 invoke 获取到结果
+	result, err := r.InvokeContext(ctx, msg.Channel, msg.Args...)
+
+	// 对端已取消，无须回复
+	if context.Cause(ctx) == errPeerCanceled {
+		log.Debug("对端取消调用: %s %s", msg.Channel, msg.ID)
+		return
+	}
+
+	if err != nil {
+		result = nil
+	}
+
+	reply := Message{
+		ID:      "",
+		ReplyId: msg.ID,
+		Error:   ToError(err),
+		Result:  result,
+	}
+
+	// 返回值无法序列化（如循环引用）时，改为回复错误，避免对端一直等待
+	if err := r.send(t, reply); err != nil {
+		reply.Result, reply.Error = nil, ToError(err)
+		r.send(t, reply)
+	}
+}
+
+func (r *Router) handleReply(msg *Message) {
+	if msg.ReplyId == "" {
+		return
+	}
+
+	var err error
+	if msg.Error != nil {
+		err = msg.Error
+	}
+
+	if !r.pending.Resolve(msg.ReplyId, msg.Result, err) {
+		log.Debug("ipc reply %s 已超时或被取消，忽略", msg.ReplyId)
+	}
+}
+
+// 直接调用对端的通道并等待回复，不要求通道已登记
+func (r *Router) Call(ctx context.Context, t Transport, channel string, args ...interface{}) (interface{}, error) {
+	return await(ctx, func(ctx context.Context, cb resultCallback) {
+		r.request(ctx, t, channel, args, cb)
+	})
+}
+
+// 直接调用对端的通道，不等待回复
+func (r *Router) Notify(t Transport, channel string, args ...interface{}) error {
+	return r.send(t, Message{
+		ID:      "", // ID 为空则不需要回复
+		Channel: channel,
+		Args:    args,
+	})
+}
+
+// 发送需要回复的消息，回复由 cb 接收
+func (r *Router) request(ctx context.Context, t Transport, channel string, args []interface{}, cb resultCallback) {
+
+	// 添加到等待结果的表，由表负责超时；随机 ID 与等待中的调用重复时重新生成
+	timeout := r.timeoutOf(channel)
+	if deadline, ok := ctx.Deadline(); ok {
+		// ctx 设置了 deadline 时以其为准，未设置时才使用通道的超时时间
+		if timeout = time.Until(deadline); timeout <= 0 {
+			timeout = time.Nanosecond
+		}
+	}
+	id := utils.RandString(8)
+	for !r.pending.Add(id, channel, t, timeout, cb) {
+		id = utils.RandString(8)
+	}
+
+	// ctx 取消后，不再等待对端的回复
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			r.pending.Cancel(id, ctx.Err())
+		}()
+	}
+
+	err := r.send(t, Message{
+		ID:      id,
+		Channel: channel,
+		Args:    args,
+	})
+	if err != nil {
+		r.pending.Cancel(id, err)
+	}
+}
+
+func (r *Router) send(t Transport, msg Message) error {
+	// 封装二进制数据
+	var err error
+	if msg.Args, err = encodeBinaryValues(msg.Args); err == nil {
+		msg.Result, err = encodeBinary(msg.Result)
+	}
+	if err != nil {
+		log.Error("IPC 消息封装二进制数据出错: %s", err.Error())
+		return err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Error("IPC 消息 JSON 序列化出错: %s", err.Error())
+		return err
+	}
+
+	r.record(DIRECTION_OUT, t, string(data))
+
+	return r.sendData(t, string(data))
+}
@@ -0,0 +1,425 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 两个通过内存传输相连的 Router，client 经由 ct 调用 server 注册的通道
+func newRouterPair(t *testing.T) (client *Router, ct *MemoryTransport, server *Router, st *MemoryTransport) {
+	t.Helper()
+
+	ct, st = NewMemoryTransport()
+	client, server = NewRouter(), NewRouter()
+	client.Attach(ct)
+	server.Attach(st)
+
+	t.Cleanup(func() {
+		ct.Close()
+	})
+
+	return client, ct, server, st
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func asError(t *testing.T, err error) *Error {
+	t.Helper()
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("期望 *Error，实际为 %T: %v", err, err)
+	}
+	return e
+}
+
+func TestRouterInvokeReply(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("add", func(a, b int) int {
+		return a + b
+	})
+
+	result, err := client.Call(context.Background(), ct, "add", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != float64(3) {
+		t.Fatalf("result = %#v, want 3", result)
+	}
+
+	stats := client.PendingStats()
+	if stats.InFlight != 0 || stats.Completed != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestRouterInvokeRemote(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("echo", func(s string) string {
+		return s
+	})
+
+	// 对端注册的通道经由 Invoke 调用
+	client.RegisterRemote(ct, "echo")
+
+	result, err := client.Invoke("echo", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "hi" {
+		t.Fatalf("result = %#v, want hi", result)
+	}
+}
+
+func TestRouterTimeout(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	canceled := make(chan struct{})
+	server.Handle("slow", func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	}, WithTimeout(time.Second))
+
+	client.SetChannelTimeout("slow", 50*time.Millisecond)
+
+	start := time.Now()
+	_, err := client.Call(context.Background(), ct, "slow")
+	if e := asError(t, err); e.Code != ERR_TIMEOUT {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_TIMEOUT)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("超时过晚: %s", elapsed)
+	}
+
+	stats := client.PendingStats()
+	if stats.InFlight != 0 || stats.Timeouts != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// server 端按自己的超时时间结束 handler
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server handler 未被取消")
+	}
+}
+
+type codedErr struct{}
+
+func (codedErr) Error() string { return "coded" }
+
+func (codedErr) IPCError() (string, interface{}) { return "E_CUSTOM", map[string]interface{}{"n": 1} }
+
+func TestRouterErrorPropagation(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("plain", func() error {
+		return errors.New("boom")
+	})
+	server.Handle("coded", func() (int, error) {
+		return 0, codedErr{}
+	})
+	server.Handle("typed", func(n int) int {
+		return n
+	})
+
+	_, err := client.Call(context.Background(), ct, "plain")
+	if e := asError(t, err); e.Message != "boom" || e.Code != "" {
+		t.Fatalf("plain: %+v", e)
+	}
+
+	_, err = client.Call(context.Background(), ct, "coded")
+	e := asError(t, err)
+	if e.Code != "E_CUSTOM" || e.Message != "coded" {
+		t.Fatalf("coded: %+v", e)
+	}
+	if data, _ := json.Marshal(e.Data); string(data) != `{"n":1}` {
+		t.Fatalf("coded data = %s", data)
+	}
+
+	_, err = client.Call(context.Background(), ct, "typed", "abc")
+	if e := asError(t, err); e.Code != ERR_ARGUMENT {
+		t.Fatalf("typed: %+v", e)
+	}
+
+	_, err = client.Call(context.Background(), ct, "missing")
+	if e := asError(t, err); e.Code != ERR_NOT_FOUND {
+		t.Fatalf("missing: %+v", e)
+	}
+}
+
+func TestRouterCallerCancel(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	started := make(chan struct{})
+	server.Handle("wait", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := client.Call(ctx, ct, "wait")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	waitFor(t, "pending 计数", func() bool {
+		stats := client.PendingStats()
+		return stats.InFlight == 0 && stats.Canceled == 1
+	})
+}
+
+func TestRouterPeerCancel(t *testing.T) {
+	server := NewRouter()
+	ct, st := NewMemoryTransport()
+	defer ct.Close()
+	server.Attach(st)
+
+	replies := make(chan string, 4)
+	ct.OnReceive(func(data string) {
+		replies <- data
+	})
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	server.Handle("wait", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+	})
+
+	send := func(msg Message) {
+		data, _ := json.Marshal(msg)
+		if err := ct.Send(string(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send(Message{ID: "1", Channel: "wait"})
+	<-started
+
+	send(Message{ReplyId: "1", Type: TYPE_CANCEL})
+
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler 的 ctx 未被取消")
+	}
+
+	// 对端已取消，不应再回复
+	select {
+	case data := <-replies:
+		t.Fatalf("收到了回复: %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRouterReset(t *testing.T) {
+	client := NewRouter()
+	ct, st := NewMemoryTransport()
+	defer ct.Close()
+	client.Attach(ct)
+
+	// 对端收到调用后不回复
+	received := make(chan struct{}, 1)
+	st.OnReceive(func(data string) {
+		received <- struct{}{}
+	})
+
+	client.RegisterRemote(ct, "js.wait")
+	if !client.HasChannel("js.wait") {
+		t.Fatal("通道未登记")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.InvokeContext(context.Background(), "js.wait")
+		done <- err
+	}()
+
+	<-received
+	client.Reset(ct)
+
+	select {
+	case err := <-done:
+		if e := asError(t, err); e.Code != ERR_CANCELED {
+			t.Fatalf("code = %q, want %q", e.Code, ERR_CANCELED)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Reset 后调用未结束")
+	}
+
+	if client.HasChannel("js.wait") {
+		t.Fatal("Reset 后通道仍存在")
+	}
+
+	stats := client.PendingStats()
+	if stats.InFlight != 0 || stats.Canceled != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestRouterRegisterRemoteKeepsGoHandler(t *testing.T) {
+	client, ct, server, st := newRouterPair(t)
+
+	server.Handle("echo", func(s string) string {
+		return "go:" + s
+	})
+
+	// 对端不能覆盖 GO 注册的通道
+	err := server.RegisterRemote(st, "echo")
+	if e := asError(t, err); e.Code != ERR_FORBIDDEN {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_FORBIDDEN)
+	}
+	if remotes := server.Remotes("echo"); len(remotes) != 0 {
+		t.Fatalf("remotes = %v", remotes)
+	}
+
+	result, err := client.Call(context.Background(), ct, "echo", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "go:hi" {
+		t.Fatalf("result = %#v, want go:hi", result)
+	}
+
+	// 对端注册的通道可重复注册
+	if err := server.RegisterRemote(st, "js.echo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterRemote(st, "js.echo"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouterContextDeadlineOverridesTimeout(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("js.slow", func() string {
+		time.Sleep(150 * time.Millisecond)
+		return "done"
+	})
+	client.RegisterRemote(ct, "js.slow")
+
+	// 默认超时短于 ctx 的 deadline 时，以 ctx 为准
+	client.SetTimeout(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := client.InvokeContext(ctx, "js.slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "done" {
+		t.Fatalf("result = %#v, want done", result)
+	}
+
+	// 未设置 deadline 时仍使用默认超时
+	_, err = client.InvokeContext(context.Background(), "js.slow")
+	if e := asError(t, err); e.Code != ERR_TIMEOUT {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_TIMEOUT)
+	}
+
+	// ctx 的 deadline 短于默认超时时同样以 ctx 为准
+	client.SetTimeout(time.Second)
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.InvokeContext(ctx, "js.slow"); err == nil {
+		t.Fatal("应超时")
+	}
+	if elapsed := time.Since(start); elapsed > 120*time.Millisecond {
+		t.Fatalf("超时过晚: %s", elapsed)
+	}
+}
+
+// CallJsFunc 经由 Call 调用，ctx 的 deadline 长于默认超时时不应被截断
+func TestRouterCallContextDeadline(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("callJsFunc", func(fn string) string {
+		time.Sleep(150 * time.Millisecond)
+		return fn
+	})
+
+	client.SetTimeout(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := client.Call(ctx, ct, "callJsFunc", "app.ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "app.ready" {
+		t.Fatalf("result = %#v", result)
+	}
+
+	_, err = client.Call(context.Background(), ct, "callJsFunc", "app.ready")
+	if e := asError(t, err); e.Code != ERR_TIMEOUT {
+		t.Fatalf("code = %q, want %q", e.Code, ERR_TIMEOUT)
+	}
+}
+
+// 通道单独设置的参数转换方式优先于 Router 的设置
+func TestRouterStrictArgsOverride(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	double := func(n int) int {
+		return n * 2
+	}
+	server.Handle("default", double)
+	server.Handle("strict", double, WithStrictArgs(true))
+	server.Handle("loose", double, WithStrictArgs(false))
+
+	for _, tt := range []struct {
+		routerStrict bool
+		channel      string
+		accept       bool
+	}{
+		{true, "default", false},
+		{true, "strict", false},
+		{true, "loose", true},
+		{false, "default", true},
+		{false, "strict", false},
+		{false, "loose", true},
+	} {
+		server.SetStrictArgs(tt.routerStrict)
+
+		result, err := client.Call(context.Background(), ct, tt.channel, "4")
+		if tt.accept {
+			if err != nil || result != float64(8) {
+				t.Errorf("router strict=%v %s: result = %#v, %v", tt.routerStrict, tt.channel, result, err)
+			}
+			continue
+		}
+
+		e := asError(t, err)
+		data, _ := e.Data.(map[string]interface{})
+		if e.Code != ERR_ARGUMENT || data["index"] != float64(0) || data["expected"] != "int" || data["actual"] != "string" {
+			t.Errorf("router strict=%v %s: err = %+v", tt.routerStrict, tt.channel, e)
+		}
+	}
+}
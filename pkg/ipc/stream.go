@@ -0,0 +1,194 @@
+package ipc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 未被对端确认消费的最大数据条数，超过后 Write 将阻塞（背压）
+const streamWindow = 16
+
+var streamType = reflect.TypeOf((*Stream)(nil))
+
+type streamCtxKey struct{}
+
+func withStream(ctx context.Context, stream *Stream) context.Context {
+	return context.WithValue(ctx, streamCtxKey{}, stream)
+}
+
+func streamFromContext(ctx context.Context) (*Stream, bool) {
+	stream, ok := ctx.Value(streamCtxKey{}).(*Stream)
+	return stream, ok
+}
+
+// 流式 handler 的写入端
+//
+// GO handler 将 *Stream 声明为参数（不占用调用方传入的参数位置），即可通过 Write 向对端持续推送数据
+type Stream struct {
+	id   string
+	ctx  context.Context
+	send func(msg Message)
+
+	credits chan struct{}
+}
+
+func newStream(ctx context.Context, id string, send func(msg Message)) *Stream {
+	return &Stream{
+		id:      id,
+		ctx:     ctx,
+		send:    send,
+		credits: make(chan struct{}, streamWindow),
+	}
+}
+
+// 流的上下文，对端取消或调用结束后被取消
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// 推送一条数据到对端
+//
+// 当对端未消费的数据达到上限时阻塞，直到对端确认消费或流被取消
+func (s *Stream) Write(data interface{}) error {
+	select {
+	case s.credits <- struct{}{}:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+
+	s.send(Message{
+		ReplyId: s.id,
+		Type:    TYPE_CHUNK,
+		Result:  data,
+	})
+
+	return nil
+}
+
+// 对端已消费一条数据，释放一个发送额度
+func (s *Stream) ack() {
+	select {
+	case <-s.credits:
+	default:
+	}
+}
+
+// 将 handler 返回的 chan 逐条写入 stream，直到 chan 关闭
+func pumpStream(ctx context.Context, stream *Stream, ch reflect.Value) error {
+	if ch.IsNil() {
+		return nil
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+
+	for {
+		chosen, val, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return ctx.Err()
+		}
+		if !ok {
+			return nil
+		}
+		if err := stream.Write(val.Interface()); err != nil {
+			return err
+		}
+	}
+}
+
+type streamEntry struct {
+	stream *Stream
+	cancel context.CancelFunc
+}
+
+// 正在进行中的流，key 为发起的对端及其消息 ID，对端只能控制自己发起的流
+type streams struct {
+	mu      sync.Mutex
+	entries map[peerKey]streamEntry
+}
+
+func newStreams() *streams {
+	return &streams{
+		entries: make(map[peerKey]streamEntry),
+	}
+}
+
+func (s *streams) Add(t Transport, id string, entry streamEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[peerKey{t, id}] = entry
+}
+
+func (s *streams) Del(t Transport, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, peerKey{t, id})
+}
+
+func (s *streams) Get(t Transport, id string) (streamEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, exist := s.entries[peerKey{t, id}]
+	return entry, exist
+}
+
+// 取消由 t 发起的所有流
+func (s *streams) CancelBy(t Transport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.entries {
+		if key.transport == t {
+			entry.cancel()
+		}
+	}
+}
+
+// 对端发起的流式调用
+func (r *Router) streamByPeer(t Transport, msg *Message) {
+
+	ctx, cancel := context.WithCancel(withTransport(context.Background(), t))
+	defer cancel()
+
+	stream := newStream(ctx, msg.ID, func(m Message) {
+		r.send(t, m)
+	})
+
+	r.streams.Add(t, msg.ID, streamEntry{stream, cancel})
+	defer r.streams.Del(t, msg.ID)
+
+	// handler 的返回值（如有）随结束消息一起发送
+	result, err := r.InvokeContext(withStream(ctx, stream), msg.Channel, msg.Args...)
+
+	if err != nil {
+		result = nil
+	}
+
+	r.send(t, Message{
+		ReplyId: msg.ID,
+		Type:    TYPE_END,
+		Result:  result,
+		Error:   ToError(err),
+	})
+}
+
+// 对端对流的控制消息（取消、确认消费）
+func (r *Router) handleStreamControl(t Transport, msg *Message) {
+
+	entry, exist := r.streams.Get(t, msg.ReplyId)
+	if !exist {
+		return
+	}
+
+	switch msg.Type {
+	case TYPE_ACK:
+		entry.stream.ack()
+	case TYPE_CANCEL:
+		log.Debug("对端取消 stream: %s", msg.ReplyId)
+		entry.cancel()
+	}
+}
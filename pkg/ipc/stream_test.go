@@ -0,0 +1,225 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 模拟发起流式调用的对端，直接收发原始消息
+type streamPeer struct {
+	t        *testing.T
+	pt       *MemoryTransport
+	received chan Message
+}
+
+func newStreamPeer(t *testing.T, server *Router) *streamPeer {
+	t.Helper()
+
+	pt, st := NewMemoryTransport()
+	server.Attach(st)
+	t.Cleanup(func() {
+		pt.Close()
+	})
+
+	p := &streamPeer{t: t, pt: pt, received: make(chan Message, 256)}
+	pt.OnReceive(func(data string) {
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Errorf("消息解析出错: %v", err)
+			return
+		}
+		p.received <- msg
+	})
+
+	return p
+}
+
+func (p *streamPeer) send(msg Message) {
+	p.t.Helper()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if err := p.pt.Send(string(data)); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// 等待下一条消息
+func (p *streamPeer) next() Message {
+	p.t.Helper()
+
+	select {
+	case msg := <-p.received:
+		return msg
+	case <-time.After(2 * time.Second):
+		p.t.Fatal("等待消息超时")
+		return Message{}
+	}
+}
+
+// 确认在 d 时间内没有收到消息
+func (p *streamPeer) idle(d time.Duration) {
+	p.t.Helper()
+
+	select {
+	case msg := <-p.received:
+		p.t.Fatalf("不应收到消息: %+v", msg)
+	case <-time.After(d):
+	}
+}
+
+// 未确认消费的数据达到 streamWindow 后，Write 阻塞，直到对端 ACK
+func TestStreamBackpressure(t *testing.T) {
+	server := NewRouter()
+
+	written := make(chan int, 100)
+	server.Handle("count", func(stream *Stream, n int) (string, error) {
+		for i := 0; i < n; i++ {
+			if err := stream.Write(i); err != nil {
+				return "", err
+			}
+			written <- i
+		}
+		return "done", nil
+	})
+
+	p := newStreamPeer(t, server)
+	p.send(Message{ID: "s1", Type: TYPE_STREAM, Channel: "count", Args: []interface{}{streamWindow + 4}})
+
+	for i := 0; i < streamWindow; i++ {
+		msg := p.next()
+		if msg.Type != TYPE_CHUNK || msg.ReplyId != "s1" || msg.Result != float64(i) {
+			t.Fatalf("chunk %d = %+v", i, msg)
+		}
+	}
+	p.idle(50 * time.Millisecond)
+	if len(written) != streamWindow {
+		t.Fatalf("未 ACK 时写入了 %d 条，期望 %d 条", len(written), streamWindow)
+	}
+
+	// 每确认一条，释放一条额度
+	for i := 0; i < 4; i++ {
+		p.send(Message{ReplyId: "s1", Type: TYPE_ACK})
+		if msg := p.next(); msg.Type != TYPE_CHUNK || msg.Result != float64(streamWindow+i) {
+			t.Fatalf("chunk %d = %+v", streamWindow+i, msg)
+		}
+	}
+
+	// handler 的返回值随结束消息一起发送
+	if msg := p.next(); msg.Type != TYPE_END || msg.ReplyId != "s1" || msg.Result != "done" || msg.Error != nil {
+		t.Fatalf("end = %+v", msg)
+	}
+}
+
+// 对端取消后，阻塞的 Write 返回，handler 的 ctx 被取消
+func TestStreamCancel(t *testing.T) {
+	server := NewRouter()
+
+	canceled := make(chan error, 1)
+	server.Handle("forever", func(ctx context.Context, stream *Stream) error {
+		for i := 0; ; i++ {
+			if err := stream.Write(i); err != nil {
+				canceled <- ctx.Err()
+				return err
+			}
+		}
+	})
+
+	p := newStreamPeer(t, server)
+	p.send(Message{ID: "s1", Type: TYPE_STREAM, Channel: "forever"})
+	for i := 0; i < streamWindow; i++ {
+		p.next()
+	}
+
+	// 其他对端无法取消该流
+	other := newStreamPeer(t, server)
+	other.send(Message{ReplyId: "s1", Type: TYPE_CANCEL})
+	p.idle(50 * time.Millisecond)
+
+	p.send(Message{ReplyId: "s1", Type: TYPE_CANCEL})
+
+	select {
+	case err := <-canceled:
+		if err != context.Canceled {
+			t.Fatalf("ctx.Err() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("取消后 Write 未返回")
+	}
+
+	if msg := p.next(); msg.Type != TYPE_END || msg.Error == nil || msg.Error.Code != ERR_CANCELED {
+		t.Fatalf("end = %+v", msg)
+	}
+}
+
+// 返回 chan 的 handler：逐条推送直到 chan 关闭，取消时停止读取
+func TestStreamChanHandler(t *testing.T) {
+	server := NewRouter()
+
+	stopped := make(chan struct{})
+	server.Handle("ticks", func(ctx context.Context, n int) <-chan int {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			for i := 0; i < n; i++ {
+				select {
+				case ch <- i:
+				case <-ctx.Done():
+					close(stopped)
+					return
+				}
+			}
+		}()
+		return ch
+	})
+
+	p := newStreamPeer(t, server)
+	p.send(Message{ID: "s1", Type: TYPE_STREAM, Channel: "ticks", Args: []interface{}{3}})
+	for i := 0; i < 3; i++ {
+		if msg := p.next(); msg.Type != TYPE_CHUNK || msg.Result != float64(i) {
+			t.Fatalf("chunk %d = %+v", i, msg)
+		}
+		p.send(Message{ReplyId: "s1", Type: TYPE_ACK})
+	}
+	if msg := p.next(); msg.Type != TYPE_END || msg.Error != nil {
+		t.Fatalf("end = %+v", msg)
+	}
+
+	p.send(Message{ID: "s2", Type: TYPE_STREAM, Channel: "ticks", Args: []interface{}{1000}})
+	p.next()
+	p.send(Message{ReplyId: "s2", Type: TYPE_CANCEL})
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("取消后 handler 的 ctx 未被取消")
+	}
+}
+
+// 流式通道不能通过普通的 Invoke 调用
+func TestStreamPlainInvoke(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	server.Handle("write", func(stream *Stream) error { return nil })
+	server.Handle("chan", func() <-chan int { return nil })
+
+	for _, channel := range []string{"write", "chan"} {
+		if _, err := server.Invoke(channel); err == nil || !strings.Contains(err.Error(), "ipc.stream") {
+			t.Errorf("%s: Invoke err = %v", channel, err)
+		}
+		if _, err := client.Call(context.Background(), ct, channel); err == nil || !strings.Contains(err.Error(), "ipc.stream") {
+			t.Errorf("%s: Call err = %v", channel, err)
+		}
+	}
+
+	info, _ := server.GetChannel("chan")
+	if !info.Stream || info.Result == nil || info.Result.Kind() != reflect.Int {
+		t.Fatalf("info = %+v", info)
+	}
+}
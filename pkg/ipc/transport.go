@@ -0,0 +1,129 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 传输层，负责与一个对端（如一个页面里的 JS）收发消息
+//
+// 一个 Transport 对应一个对端，通过 Router.Attach 接入
+type Transport interface {
+	// 发送一条消息到对端
+	Send(data string) error
+	// 设置收到对端消息时的回调，由 Router.Attach 调用
+	OnReceive(fn func(data string))
+}
+
+var ErrClosed = errors.New("ipc transport closed")
+
+type transportCtxKey struct{}
+
+func withTransport(ctx context.Context, t Transport) context.Context {
+	return context.WithValue(ctx, transportCtxKey{}, t)
+}
+
+// 获取发起调用的对端
+//
+// 仅对端发起的调用，handler 收到的 ctx 里才有 Transport；GO 直接 Invoke 时返回 false
+func TransportFromContext(ctx context.Context) (Transport, bool) {
+	t, ok := ctx.Value(transportCtxKey{}).(Transport)
+	return t, ok
+}
+
+// 内存传输，成对创建，一端发送的消息按顺序在另一端接收
+//
+// 不依赖浏览器，可用于在任意平台上测试 GO 与对端之间的调用
+type MemoryTransport struct {
+	peer *MemoryTransport
+
+	mu      sync.Mutex
+	receive func(data string)
+	origin  string
+
+	queue  chan string
+	closed chan struct{} // 两端共用
+	once   *sync.Once
+}
+
+// 创建一对相连的内存传输
+func NewMemoryTransport() (*MemoryTransport, *MemoryTransport) {
+	closed := make(chan struct{})
+	once := &sync.Once{}
+
+	a := &MemoryTransport{queue: make(chan string, 64), closed: closed, once: once}
+	b := &MemoryTransport{queue: make(chan string, 64), closed: closed, once: once}
+	a.peer, b.peer = b, a
+
+	go a.loop()
+	go b.loop()
+
+	return a, b
+}
+
+func (t *MemoryTransport) Send(data string) error {
+	select {
+	case <-t.closed:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case t.peer.queue <- data:
+		return nil
+	case <-t.closed:
+		return ErrClosed
+	}
+}
+
+func (t *MemoryTransport) OnReceive(fn func(data string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.receive = fn
+}
+
+// 设置对端的来源，即经由本端（Attach 到 Router 的一端）发起调用的来源，用于测试 WithOrigins
+func (t *MemoryTransport) SetOrigin(origin string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.origin = origin
+}
+
+func (t *MemoryTransport) Origin() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.origin
+}
+
+// 关闭两端，之后 Send 返回 ErrClosed
+func (t *MemoryTransport) Close() error {
+	t.once.Do(func() {
+		close(t.closed)
+	})
+	return nil
+}
+
+// 逐条分派收到的消息，未设置回调时丢弃
+func (t *MemoryTransport) loop() {
+	for {
+		select {
+		case data := <-t.queue:
+			t.mu.Lock()
+			fn := t.receive
+			t.mu.Unlock()
+
+			if fn != nil {
+				fn(data)
+			}
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+// 对端发来的消息 ID 只在该对端内唯一
+type peerKey struct {
+	transport Transport
+	id        string
+}
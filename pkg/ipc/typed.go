@@ -0,0 +1,166 @@
+package ipc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/epkgs/blink/internal/cast"
+	"github.com/epkgs/blink/internal/log"
+)
+
+// 参数错误
+type ArgumentError struct {
+	Channel  string // 通道
+	Index    int    // 参数位置，从 0 开始
+	Expected string // 期望的 GO 类型
+	Actual   string // 实际传入值的 JS 类型
+	Err      error  // 原始错误
+}
+
+func (e *ArgumentError) Error() string {
+	if e.Actual != "" {
+		return fmt.Sprintf("ipc channel %s 第 %d 个参数错误，期望类型 %s，实际类型 %s: %v", e.Channel, e.Index, e.Expected, e.Actual, e.Err)
+	}
+	return fmt.Sprintf("ipc channel %s 第 %d 个参数错误，期望类型 %s: %v", e.Channel, e.Index, e.Expected, e.Err)
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ArgumentError) IPCError() (code string, data interface{}) {
+	return ERR_ARGUMENT, map[string]interface{}{
+		"index":    e.Index,
+		"expected": e.Expected,
+		"actual":   e.Actual,
+	}
+}
+
+// 设置是否严格转换 Handle 注册的 handler 的参数，默认为严格转换
+//
+// 严格转换时，类型不匹配或有损的转换（如 "abc" -> int、1.5 -> int、数组 -> 结构体）返回 *ArgumentError；
+// 宽松转换时，无法转换的参数取零值。单个通道可通过 WithStrictArgs 单独设置
+func (r *Router) SetStrictArgs(strict bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lenientArgs = !strict
+}
+
+// 设置参数为结构体时，字段名称精确匹配失败后是否忽略大小写再匹配一次（如 {"Name": "a"} 匹配 `json:"name"`），默认开启
+//
+// 全局生效，对所有 Router 及 BindObject 的属性读写都有效
+func SetCaseInsensitiveFields(enable bool) {
+	cast.SetFoldCase(enable)
+}
+
+// 通道是否严格转换参数，channel 为通道单独的设置
+func (r *Router) strictArgs(channel *bool) bool {
+	if channel != nil {
+		return *channel
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.lenientArgs
+}
+
+// 注册强类型的 GO handler
+//
+// 调用方须传入 1 个参数，以 JSON 的方式严格解码到 Req：类型不匹配、存在 Req 中未定义的字段，都将返回 *ArgumentError
+func HandleTyped[Req, Resp any](r *Router, channel string, fn func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) {
+
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	respType := reflect.TypeOf((*Resp)(nil)).Elem()
+
+	h := func(ctx context.Context, cb resultCallback, args ...interface{}) {
+
+		reply := func(result interface{}, err error) {
+			if cb != nil {
+				cb(result, err)
+			}
+		}
+
+		var req Req
+		if err := decodeStrict(args, &req); err != nil {
+			argErr := &ArgumentError{
+				Channel:  channel,
+				Index:    0,
+				Expected: reqType.String(),
+				Err:      err,
+			}
+			if len(args) > 0 {
+				argErr.Actual = cast.JSTypeOf(args[0])
+			}
+			reply(nil, argErr)
+			return
+		}
+
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err, ok := r.(error)
+					if !ok {
+						err = fmt.Errorf("%v", r)
+					}
+					log.Error("panic by ipc handler[ %v ]: %v", channel, err)
+					reply(nil, err)
+				}
+			}()
+
+			resp, err := fn(ctx, req)
+			if err != nil {
+				reply(nil, err)
+				return
+			}
+			reply(resp, nil)
+		}()
+	}
+
+	r.register(channel, h, ChannelInfo{
+		Channel: channel,
+		Source:  SOURCE_GO,
+		Params:  []reflect.Type{reqType},
+		Result:  respType,
+	}, opts)
+}
+
+// 将唯一的参数严格解码到 out
+func decodeStrict(args []interface{}, out interface{}) error {
+	if len(args) != 1 {
+		return fmt.Errorf("需要 1 个参数，实际传入 %d 个", len(args))
+	}
+
+	data, err := json.Marshal(args[0])
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(out)
+}
+
+// 将对端传来的值转换为 T：值本身即为 T 时直接返回，为 nil 时返回零值，否则经由 JSON 转换
+func Decode[T any](v interface{}) (T, error) {
+	var out T
+
+	if v == nil {
+		return out, nil
+	}
+
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return out, err
+	}
+
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
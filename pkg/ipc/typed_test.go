@@ -0,0 +1,138 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type typedReq struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type typedResp struct {
+	ID string `json:"id"`
+}
+
+type typedCtxKey struct{}
+
+func TestHandleTyped(t *testing.T) {
+	client, ct, server, _ := newRouterPair(t)
+
+	HandleTyped(server, "create", func(ctx context.Context, req typedReq) (typedResp, error) {
+		switch req.Name {
+		case "coded":
+			return typedResp{}, codedErr{}
+		case "plain":
+			return typedResp{}, errors.New("plain")
+		case "panic":
+			panic("oops")
+		}
+		return typedResp{ID: req.Name + strings.Repeat("!", req.Count)}, nil
+	})
+
+	result, err := client.Call(context.Background(), ct, "create", map[string]interface{}{"name": "a", "count": 2, "tags": []string{"x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"id": "a!!"}; !reflect.DeepEqual(result, want) {
+		t.Fatalf("result = %#v, want %#v", result, want)
+	}
+
+	// 参数错误：未定义的字段、类型不匹配、参数个数不为 1
+	for _, tt := range []struct {
+		name   string
+		args   []interface{}
+		actual interface{}
+	}{
+		{"未定义的字段", []interface{}{map[string]interface{}{"name": "a", "extra": 1}}, "object"},
+		{"类型不匹配", []interface{}{map[string]interface{}{"name": "a", "count": "2"}}, "object"},
+		{"小数", []interface{}{map[string]interface{}{"count": 1.5}}, "object"},
+		{"数组", []interface{}{[]interface{}{"a", 2}}, "array"},
+		{"缺少参数", nil, ""},
+		{"多余参数", []interface{}{map[string]interface{}{}, 1}, "object"},
+	} {
+		_, err := client.Call(context.Background(), ct, "create", tt.args...)
+		e := asError(t, err)
+		if e.Code != ERR_ARGUMENT {
+			t.Errorf("%s: code = %q, want %q", tt.name, e.Code, ERR_ARGUMENT)
+			continue
+		}
+		data, _ := e.Data.(map[string]interface{})
+		if data["index"] != float64(0) || data["expected"] != "ipc.typedReq" || data["actual"] != tt.actual {
+			t.Errorf("%s: data = %#v", tt.name, e.Data)
+		}
+	}
+
+	// handler 返回的错误原样传递给调用方
+	for _, tt := range []struct {
+		name, code, message string
+	}{
+		{"coded", "E_CUSTOM", "coded"},
+		{"plain", "", "plain"},
+		{"panic", "", "oops"},
+	} {
+		_, err := client.Call(context.Background(), ct, "create", map[string]interface{}{"name": tt.name})
+		if e := asError(t, err); e.Code != tt.code || e.Message != tt.message {
+			t.Errorf("%s: err = %+v, want code %q message %q", tt.name, e, tt.code, tt.message)
+		}
+	}
+}
+
+// GO 端调用时，ctx 传递给 handler，请求可直接传入 Req 类型的值
+func TestHandleTypedLocal(t *testing.T) {
+	r := NewRouter()
+
+	HandleTyped(r, "echo", func(ctx context.Context, req typedReq) (*typedResp, error) {
+		v, _ := ctx.Value(typedCtxKey{}).(string)
+		return &typedResp{ID: v + req.Name}, nil
+	})
+
+	ctx := context.WithValue(context.Background(), typedCtxKey{}, "ctx-")
+	result, err := r.InvokeContext(ctx, "echo", typedReq{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, ok := result.(*typedResp); !ok || resp.ID != "ctx-a" {
+		t.Fatalf("result = %#v", result)
+	}
+
+	info, exist := r.GetChannel("echo")
+	if !exist {
+		t.Fatal("未注册通道 echo")
+	}
+	if len(info.Params) != 1 || info.Params[0] != reflect.TypeOf(typedReq{}) || info.Result != reflect.TypeOf(&typedResp{}) || info.Variadic || info.Stream {
+		t.Fatalf("info = %+v", info)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	req := typedReq{Name: "a"}
+	if got, err := Decode[typedReq](req); err != nil || !reflect.DeepEqual(got, req) {
+		t.Fatalf("相同类型: %#v, %v", got, err)
+	}
+
+	if got, err := Decode[*typedReq](nil); err != nil || got != nil {
+		t.Fatalf("nil: %#v, %v", got, err)
+	}
+
+	got, err := Decode[typedReq](map[string]interface{}{"name": "b", "count": float64(3), "tags": []interface{}{"x"}})
+	if want := (typedReq{Name: "b", Count: 3, Tags: []string{"x"}}); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("map: %#v, %v", got, err)
+	}
+
+	if n, err := Decode[int](float64(7)); err != nil || n != 7 {
+		t.Fatalf("数字: %v, %v", n, err)
+	}
+
+	if _, err := Decode[int]("7"); err == nil {
+		t.Fatal("字符串转为 int 应返回错误")
+	}
+	if _, err := Decode[typedReq](map[string]interface{}{"count": "x"}); err == nil {
+		t.Fatal("类型不匹配应返回错误")
+	}
+}
@@ -2,13 +2,18 @@ package utils
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 	"unsafe"
 )
 
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
-var src = rand.NewSource(time.Now().UnixNano())
+// rand.NewSource 返回的 Source 不是并发安全的，由 srcMu 保护
+var (
+	src   = rand.NewSource(time.Now().UnixNano())
+	srcMu sync.Mutex
+)
 
 const (
 	// 6 bits to represent a letter index
@@ -19,6 +24,9 @@ const (
 )
 
 func RandString(n int) string {
+	srcMu.Lock()
+	defer srcMu.Unlock()
+
 	b := make([]byte, n)
 	// A rand.Int63() generates 63 random bits, enough for letterIdMax letters!
 	for i, cache, remain := n-1, src.Int63(), letterIdMax; i >= 0; {
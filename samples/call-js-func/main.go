@@ -40,26 +40,35 @@ func main() {
 	view.OnDocumentReady(func(frame blink.WkeWebFrameHandle) {
 		// 避免阻塞主线程
 		go func() {
+			ctx := context.TODO()
+
 			// 调用func_1
-			view.CallJsFunc("func_1", "张三", 18)
+			view.CallJsFunc(ctx, "func_1", "张三", 18)
 
-			// 等待返回值
-			resp2, err := view.CallJsFunc("func_2").Await(context.TODO())
+			// 等待返回值，并转换为字符串
+			result2, err := blink.CallJsFunc[string](ctx, view, "func_2")
 			if err != nil {
 				fmt.Printf("call js func_2 error: %s\n", err)
 				return
 			}
-			result2 := (*resp2).(string) // 断言为字符串
 			fmt.Printf("func_2 result is %s\n", result2)
 
-			//获取func_3返回的非基本数据类型
-			resp3, err := view.CallJsFunc("func_3").Await(context.TODO())
+			//获取func_3返回的非基本数据类型，转换为结构体
+			type person struct {
+				Name string `json:"name"`
+				Age  int    `json:"age"`
+			}
+			result3, err := blink.CallJsFunc[person](ctx, view, "func_3")
 			if err != nil {
 				fmt.Printf("call js func_3 error: %s\n", err)
 				return
 			}
-			result3 := (*resp3).(map[string]interface{}) // wait for result
-			fmt.Printf("func_3 result is %v\n", result3)
+			fmt.Printf("func_3 result is %+v\n", result3)
+
+			// 函数不存在时返回错误
+			if _, err := view.CallJsFunc(ctx, "func_404"); err != nil {
+				fmt.Printf("call js func_404 error: %s\n", err)
+			}
 		}()
 	})
 
@@ -1,6 +1,7 @@
 package blink
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,7 +12,6 @@ import (
 	"time"
 	"unsafe"
 
-	"github.com/chebyrash/promise"
 	"github.com/epkgs/blink/internal/log"
 	"github.com/epkgs/blink/pkg/utils"
 )
@@ -140,15 +140,22 @@ func (v *View) addToPool() {
 	})
 }
 
+// 在每个 frame 创建脚本上下文时，向该 frame 注入启动脚本
 func (v *View) injectBootScripts() {
-	var script string
+	v.OnDidCreateScriptContext(func(frame WkeWebFrameHandle, context uintptr, exGroup, worldId int) {
+		var script string
 
-	for _, s := range v.mb.bootScripts {
-		script += s + ";\n"
-	}
+		mainFrame := v.IsMainFrame(frame)
+		for _, s := range v.mb.bootScripts {
+			if !mainFrame && !s.allFrames {
+				continue
+			}
+			script += s.fn(v) + ";\n"
+		}
 
-	v.OnDidCreateScriptContext(func(frame WkeWebFrameHandle, context uintptr, exGroup, worldId int) {
-		v.RunJS(script)
+		if script != "" {
+			v.RunJsByFrame(frame, script)
+		}
 	})
 }
 
@@ -418,9 +425,16 @@ func (v *View) RunJsByFrame(frame WkeWebFrameHandle, script string) JsValue {
 	return JsValue(r1)
 }
 
-func (v *View) CallJsFunc(funcName string, args ...interface{}) *promise.Promise[any] {
+// 调用页面 JS 的函数，见 IPC.CallJsFunc，需要转换返回值类型时使用 blink.CallJsFunc
+func (v *View) CallJsFunc(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+
+	return v.mb.IPC.CallJsFunc(ctx, v, funcName, args...)
+}
+
+// 调用指定 frame 里的 JS 函数，见 IPC.CallJsFuncInFrame
+func (v *View) CallJsFuncInFrame(ctx context.Context, frame WkeWebFrameHandle, funcName string, args ...interface{}) (interface{}, error) {
 
-	return v.mb.IPC.CallJsFunc(v, funcName, args...)
+	return v.mb.IPC.CallJsFuncInFrame(ctx, v, frame, funcName, args...)
 }
 
 func (v *View) OnDidCreateScriptContext(callback OnDidCreateScriptContextCallback) (stop func()) {